@@ -0,0 +1,314 @@
+package req
+
+import (
+	"context"
+	"hash/crc32"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoEndpoint returned when Balancer has no endpoint to try
+var ErrNoEndpoint = errors.New("req: no endpoint available")
+
+// Balance strategies
+const (
+	BalanceRoundRobin       = "round-robin"
+	BalanceRandom           = "random"
+	BalanceLeastOutstanding = "least-outstanding"
+	BalanceConsistentHash   = "consistent-hash"
+)
+
+// BalancerOptions options for NewBalancer
+type BalancerOptions struct {
+	Endpoints      []string                                         // Endpoints base url, e.g. http://10.0.0.1:8080/api
+	Strategy       string                                           // Strategy to pick endpoint, default round-robin
+	Key            func(r *http.Request) string                     // Key for consistent-hash, default url path
+	MaxFails       int                                              // MaxFails consecutive failures eject endpoint, default 1
+	EjectDuration  time.Duration                                    // EjectDuration of passive ejection, default 30s
+	IsFailure      func(res *http.Response, err error) bool         // IsFailure count failure, default transport error
+	HealthCheck    func(ctx context.Context, endpoint string) error // HealthCheck probe endpoint every HealthInterval
+	HealthInterval time.Duration                                    // HealthInterval enable active probe when > 0
+}
+
+type balancerEndpoint struct {
+	outstanding  int64 // first for 64-bit atomic alignment
+	raw          string
+	url          *url.URL
+	fails        int
+	ejectedUntil time.Time
+}
+
+type balancerRingPoint struct {
+	hash     uint32
+	endpoint *balancerEndpoint
+}
+
+// Balancer spread requests across endpoints, eject failed endpoints and fail over on connection error
+type Balancer struct {
+	next      uint64 // first for 64-bit atomic alignment
+	o         BalancerOptions
+	endpoints []*balancerEndpoint
+	ring      []balancerRingPoint
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+}
+
+// NewBalancer create Balancer, Close is required when HealthCheck is enabled
+func NewBalancer(o *BalancerOptions) (*Balancer, error) {
+	if o == nil || len(o.Endpoints) == 0 {
+		return nil, errors.New("NewBalancer: no endpoints")
+	}
+	b := &Balancer{o: *o}
+	if b.o.Strategy == "" {
+		b.o.Strategy = BalanceRoundRobin
+	}
+	switch b.o.Strategy {
+	case BalanceRoundRobin, BalanceRandom, BalanceLeastOutstanding, BalanceConsistentHash:
+	default:
+		return nil, errors.Errorf("NewBalancer: unsupported strategy %q", b.o.Strategy)
+	}
+	if b.o.MaxFails <= 0 {
+		b.o.MaxFails = 1
+	}
+	if b.o.EjectDuration <= 0 {
+		b.o.EjectDuration = 30 * time.Second //nolint:gomnd
+	}
+	for _, v := range o.Endpoints {
+		u, err := url.Parse(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "NewBalancer: invalid endpoint %q", v)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, errors.Errorf("NewBalancer: endpoint %q require scheme and host", v)
+		}
+		ep := &balancerEndpoint{raw: v, url: u}
+		b.endpoints = append(b.endpoints, ep)
+		for i := 0; i < 100; i++ {
+			b.ring = append(b.ring, balancerRingPoint{hash: crc32.ChecksumIEEE([]byte(v + "#" + strconv.Itoa(i))), endpoint: ep})
+		}
+	}
+	sort.Slice(b.ring, func(i, j int) bool {
+		return b.ring[i].hash < b.ring[j].hash
+	})
+
+	if b.o.HealthCheck != nil && b.o.HealthInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		b.cancel = cancel
+		go b.probe(ctx)
+	}
+	return b, nil
+}
+
+// Close stop active health check
+func (b *Balancer) Close() {
+	if b.cancel != nil {
+		b.cancel()
+	}
+}
+
+// Healthy return endpoints not ejected
+func (b *Balancer) Healthy() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []string
+	now := time.Now()
+	for _, v := range b.endpoints {
+		if !now.Before(v.ejectedUntil) {
+			out = append(out, v.raw)
+		}
+	}
+	return out
+}
+
+// Hook route request to picked endpoint, scheme host and path prefix of request url are replaced
+func (b *Balancer) Hook() Hook {
+	return Hook{
+		Name:  "Balancer",
+		Order: -10,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return b.roundTrip(next, r)
+			})
+		},
+	}
+}
+
+func (b *Balancer) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	tried := map[*balancerEndpoint]bool{}
+	lastErr := ErrNoEndpoint
+	for i := range b.endpoints {
+		ep := b.pick(r, tried)
+		if ep == nil {
+			break
+		}
+		tried[ep] = true
+
+		rr := r.Clone(r.Context())
+		rr.Host = ""
+		rr.URL.Scheme = ep.url.Scheme
+		rr.URL.Host = ep.url.Host
+		if err := joinEscapedPath(rr.URL, ep.url.EscapedPath(), r.URL.EscapedPath()); err != nil {
+			return nil, err
+		}
+		if i > 0 && r.Body != nil && r.Body != http.NoBody {
+			if r.GetBody == nil {
+				return nil, lastErr
+			}
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			rr.Body = body
+		}
+
+		atomic.AddInt64(&ep.outstanding, 1)
+		res, err := next.RoundTrip(rr)
+		onBodyClose(res, func() {
+			atomic.AddInt64(&ep.outstanding, -1)
+		})
+		if b.o.IsFailure != nil {
+			b.report(ep, b.o.IsFailure(res, err))
+		} else {
+			b.report(ep, err != nil)
+		}
+		if err == nil {
+			return res, nil
+		}
+		if r.Context().Err() != nil || !isDialError(err) {
+			// request may have reached the endpoint
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// isDialError report whether err happened before the request was sent, e.g. connection refused
+func isDialError(err error) bool {
+	var ne *net.OpError
+	return errors.As(err, &ne) && ne.Op == "dial"
+}
+
+// joinEscapedPath set path of u to base + p, escaped segments like %2F are kept
+func joinEscapedPath(u *url.URL, base, p string) error {
+	raw := strings.TrimSuffix(base, "/") + p
+	path, err := url.PathUnescape(raw)
+	if err != nil {
+		return err
+	}
+	u.Path = path
+	u.RawPath = ""
+	if u.EscapedPath() != raw {
+		u.RawPath = raw
+	}
+	return nil
+}
+
+func (b *Balancer) pick(r *http.Request, tried map[*balancerEndpoint]bool) *balancerEndpoint {
+	b.mu.Lock()
+	now := time.Now()
+	var candidates []*balancerEndpoint
+	for _, v := range b.endpoints {
+		if !tried[v] && !now.Before(v.ejectedUntil) {
+			candidates = append(candidates, v)
+		}
+	}
+	if len(candidates) == 0 {
+		// all ejected - try the rest anyway
+		for _, v := range b.endpoints {
+			if !tried[v] {
+				candidates = append(candidates, v)
+			}
+		}
+	}
+	b.mu.Unlock()
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	switch b.o.Strategy {
+	case BalanceRandom:
+		return candidates[rand.Intn(len(candidates))] //nolint:gosec
+	case BalanceLeastOutstanding:
+		offset := int(atomic.AddUint64(&b.next, 1))
+		var best *balancerEndpoint
+		for i := range candidates {
+			v := candidates[(offset+i)%len(candidates)]
+			if best == nil || atomic.LoadInt64(&v.outstanding) < atomic.LoadInt64(&best.outstanding) {
+				best = v
+			}
+		}
+		return best
+	case BalanceConsistentHash:
+		key := r.URL.Path
+		if b.o.Key != nil {
+			key = b.o.Key(r)
+		}
+		h := crc32.ChecksumIEEE([]byte(key))
+		start := sort.Search(len(b.ring), func(i int) bool {
+			return b.ring[i].hash >= h
+		})
+		for i := range b.ring {
+			p := b.ring[(start+i)%len(b.ring)]
+			for _, v := range candidates {
+				if v == p.endpoint {
+					return v
+				}
+			}
+		}
+		return candidates[0]
+	default:
+		return candidates[int(atomic.AddUint64(&b.next, 1)-1)%len(candidates)]
+	}
+}
+
+func (b *Balancer) report(ep *balancerEndpoint, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		ep.fails = 0
+		ep.ejectedUntil = time.Time{}
+		return
+	}
+	ep.fails++
+	if ep.fails >= b.o.MaxFails {
+		ep.ejectedUntil = time.Now().Add(b.o.EjectDuration)
+	}
+}
+
+func (b *Balancer) probe(ctx context.Context) {
+	ticker := time.NewTicker(b.o.HealthInterval)
+	defer ticker.Stop()
+	for {
+		for _, v := range b.endpoints {
+			err := b.o.HealthCheck(ctx, v.raw)
+			if ctx.Err() != nil {
+				return
+			}
+			b.mu.Lock()
+			if err != nil {
+				// keep ejected until next probe pass
+				v.ejectedUntil = time.Now().Add(b.o.EjectDuration + b.o.HealthInterval)
+			} else {
+				v.fails = 0
+				v.ejectedUntil = time.Time{}
+			}
+			b.mu.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
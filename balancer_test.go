@@ -0,0 +1,165 @@
+package req_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func newNamedServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(name + " " + r.URL.Path))
+	}))
+}
+
+func TestBalancer(t *testing.T) {
+	a := newNamedServer("a")
+	defer a.Close()
+	b := newNamedServer("b")
+	defer b.Close()
+	dead := newNamedServer("dead")
+	dead.Close()
+
+	fetch := func(client req.Request, path string) string {
+		out, _, err := client.With(req.Request{URL: path}).FetchString()
+		assert.NoError(t, err)
+		return out
+	}
+	{
+		lb, err := req.NewBalancer(&req.BalancerOptions{Endpoints: []string{a.URL + "/api", b.URL}})
+		assert.NoError(t, err)
+		client := req.Request{}.WithHook(lb.Hook())
+		assert.Equal(t, "a /api/x", fetch(client, "/x"))
+		assert.Equal(t, "b /x", fetch(client, "/x"))
+		assert.Equal(t, "a /api/x", fetch(client, "/x"))
+	}
+	{
+		// fail over and eject
+		lb, err := req.NewBalancer(&req.BalancerOptions{Endpoints: []string{dead.URL, a.URL}})
+		assert.NoError(t, err)
+		client := req.Request{BaseURL: "http://service"}.WithHook(lb.Hook())
+		assert.Equal(t, "a /x", fetch(client, "/x"))
+		assert.Equal(t, []string{a.URL}, lb.Healthy())
+		assert.Equal(t, "a /y", fetch(client, "/y"))
+	}
+	{
+		lb, err := req.NewBalancer(&req.BalancerOptions{Endpoints: []string{a.URL, b.URL, dead.URL}, Strategy: req.BalanceConsistentHash})
+		assert.NoError(t, err)
+		client := req.Request{}.WithHook(lb.Hook())
+		for _, v := range []string{"/1", "/2", "/3", "/4"} {
+			first := fetch(client, v)
+			for i := 0; i < 3; i++ {
+				assert.Equal(t, first, fetch(client, v))
+			}
+		}
+	}
+	{
+		for _, s := range []string{req.BalanceRandom, req.BalanceLeastOutstanding} {
+			lb, err := req.NewBalancer(&req.BalancerOptions{Endpoints: []string{a.URL, b.URL}, Strategy: s})
+			assert.NoError(t, err)
+			out := fetch(req.Request{}.WithHook(lb.Hook()), "/")
+			assert.True(t, strings.HasSuffix(out, " /"))
+		}
+	}
+	{
+		lb, err := req.NewBalancer(&req.BalancerOptions{Endpoints: []string{dead.URL}})
+		assert.NoError(t, err)
+		_, _, err = req.Request{}.WithHook(lb.Hook()).FetchString()
+		assert.Error(t, err)
+
+		_, err = req.NewBalancer(&req.BalancerOptions{Endpoints: []string{"/path"}})
+		assert.Error(t, err)
+		_, err = req.NewBalancer(&req.BalancerOptions{Endpoints: []string{a.URL}, Strategy: "unknown"})
+		assert.Error(t, err)
+	}
+}
+
+func TestBalancerFailover(t *testing.T) {
+	var calls int32
+	a := newNamedServer("a")
+	defer a.Close()
+	counted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer counted.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// close connection in the middle of response
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 10\r\n"))
+		_ = conn.Close()
+	}))
+	defer broken.Close()
+	dead := newNamedServer("dead")
+	dead.Close()
+
+	// connection refused
+	lb, err := req.NewBalancer(&req.BalancerOptions{Endpoints: []string{dead.URL, a.URL}})
+	assert.NoError(t, err)
+	out, _, err := req.Request{Method: http.MethodPost, URL: "/x", RawBody: []byte("data")}.WithHook(lb.Hook()).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "a /x", out)
+
+	// request was sent
+	lb, err = req.NewBalancer(&req.BalancerOptions{Endpoints: []string{broken.URL, counted.URL}})
+	assert.NoError(t, err)
+	_, err = req.Request{Method: http.MethodPost, URL: "/x", RawBody: []byte("data")}.WithHook(lb.Hook()).Do()
+	assert.Error(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
+
+func TestBalancerHealthCheck(t *testing.T) {
+	a := newNamedServer("a")
+	defer a.Close()
+	b := newNamedServer("b")
+	defer b.Close()
+
+	lb, err := req.NewBalancer(&req.BalancerOptions{
+		Endpoints:      []string{a.URL, b.URL},
+		HealthInterval: time.Hour,
+		HealthCheck: func(ctx context.Context, endpoint string) error {
+			if endpoint == a.URL {
+				return errors.New("unhealthy")
+			}
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	defer lb.Close()
+	assert.Eventually(t, func() bool {
+		return len(lb.Healthy()) == 1
+	}, time.Second, 10*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		out, _, err := req.Request{}.WithHook(lb.Hook()).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "b /", out)
+	}
+}
+
+func TestBalancerEscapedPath(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.RequestURI))
+	}))
+	defer s.Close()
+
+	lb, err := req.NewBalancer(&req.BalancerOptions{Endpoints: []string{s.URL + "/api%2Fv1/"}})
+	assert.NoError(t, err)
+	defer lb.Close()
+	client := req.Request{BaseURL: "http://service"}.WithHook(lb.Hook())
+	for path, expect := range map[string]string{
+		"/files/a%2Fb":   "/api%2Fv1/files/a%2Fb",
+		"/files/a b?q=1": "/api%2Fv1/files/a%20b?q=1",
+		"/plain":         "/api%2Fv1/plain",
+	} {
+		out, _, err := client.With(req.Request{URL: path}).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, expect, out, path)
+	}
+}
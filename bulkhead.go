@@ -0,0 +1,102 @@
+package req
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBulkheadFull returned when bulkhead and its queue are saturated or queue timeout
+var ErrBulkheadFull = errors.New("req: bulkhead full")
+
+// BulkheadOptions options for BulkheadHook
+type BulkheadOptions struct {
+	MaxConcurrent int                          // MaxConcurrent in-flight requests, default 10
+	MaxQueue      int                          // MaxQueue waiting requests, 0 fail fast when saturated
+	QueueTimeout  time.Duration                // QueueTimeout max wait in queue, 0 wait until request Context done
+	Key           func(r *http.Request) string // Key name of bulkhead, default one bulkhead for all requests, HostKey for per host
+}
+
+type bulkhead struct {
+	queued int32
+	slots  chan struct{}
+}
+
+// BulkheadHook limit in-flight requests, request is in-flight until response body closed
+func BulkheadHook(o *BulkheadOptions) Hook {
+	if o == nil {
+		o = &BulkheadOptions{}
+	}
+	limit := o.MaxConcurrent
+	if limit <= 0 {
+		limit = 10 //nolint:gomnd
+	}
+	var mu sync.Mutex
+	bulkheads := map[string]*bulkhead{}
+	get := func(r *http.Request) (string, *bulkhead) {
+		key := ""
+		if o.Key != nil {
+			key = o.Key(r)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		b, ok := bulkheads[key]
+		if !ok {
+			b = &bulkhead{slots: make(chan struct{}, limit)}
+			bulkheads[key] = b
+		}
+		return key, b
+	}
+	return Hook{
+		Name:  "Bulkhead",
+		Order: -35,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				key, b := get(r)
+				if err := b.acquire(r, o); err != nil {
+					return nil, errors.Wrapf(err, "bulkhead %q", key)
+				}
+				res, err := next.RoundTrip(r)
+				if err != nil {
+					<-b.slots
+					return res, err
+				}
+				onBodyClose(res, func() {
+					<-b.slots
+				})
+				return res, nil
+			})
+		},
+	}
+}
+
+func (b *bulkhead) acquire(r *http.Request, o *BulkheadOptions) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if int(atomic.AddInt32(&b.queued, 1)) > o.MaxQueue {
+		atomic.AddInt32(&b.queued, -1)
+		return ErrBulkheadFull
+	}
+	defer atomic.AddInt32(&b.queued, -1)
+
+	var timeout <-chan time.Time
+	if o.QueueTimeout > 0 {
+		timer := time.NewTimer(o.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-r.Context().Done():
+		return r.Context().Err()
+	case <-timeout:
+		return ErrBulkheadFull
+	}
+}
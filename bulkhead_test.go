@@ -0,0 +1,63 @@
+package req_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestBulkheadHook(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			started <- struct{}{}
+			<-block
+		}
+	}))
+	defer server.Close()
+
+	client := req.Request{BaseURL: server.URL}.WithHook(req.BulkheadHook(&req.BulkheadOptions{
+		MaxConcurrent: 1,
+		MaxQueue:      1,
+		QueueTimeout:  50 * time.Millisecond,
+		Key:           req.HostKey,
+	}))
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _, err := client.With(req.Request{URL: "/block"}).FetchBytes()
+		assert.NoError(t, err)
+	}()
+	<-started
+
+	var queuedErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, queuedErr = client.Do()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// queue is full
+	_, err := client.Do()
+	assert.True(t, errors.Is(err, req.ErrBulkheadFull))
+	time.Sleep(60 * time.Millisecond)
+	close(block)
+	wg.Wait()
+	// queue timeout
+	assert.True(t, errors.Is(queuedErr, req.ErrBulkheadFull))
+
+	_, _, err = client.FetchBytes()
+	assert.NoError(t, err)
+	_, _, err = client.FetchBytes()
+	assert.NoError(t, err)
+}
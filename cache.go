@@ -0,0 +1,476 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderFromCache set on responses served by CacheHook
+const HeaderFromCache = "X-From-Cache"
+
+// CacheOptions options for CacheHook
+type CacheOptions struct {
+	Storage CacheStorage     // Storage default NewMemoryCache(1000)
+	Shared  bool             // Shared act as shared cache, honour s-maxage and private
+	Now     func() time.Time // Now default time.Now
+}
+
+// CacheHook cache GET responses as RFC 9111 private cache, supports Cache-Control, Expires, Vary,
+// revalidation by ETag and Last-Modified, stale-while-revalidate and stale-if-error.
+//
+// Unsafe methods invalidate cached responses of the target uri.
+// Responses served from cache has HeaderFromCache header.
+func CacheHook(o *CacheOptions) Hook {
+	if o == nil {
+		o = &CacheOptions{}
+	}
+	c := &httpCache{o: *o}
+	if c.o.Storage == nil {
+		c.o.Storage = NewMemoryCache(1000) //nolint:gomnd
+	}
+	if c.o.Now == nil {
+		c.o.Now = time.Now
+	}
+	return Hook{
+		Name:  "Cache",
+		Order: -60,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return c.roundTrip(next, r)
+			})
+		},
+	}
+}
+
+type cacheEntry struct {
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	Vary         http.Header // Vary selected request headers
+	RequestTime  time.Time
+	ResponseTime time.Time
+}
+
+type httpCache struct {
+	o            CacheOptions
+	revalidating sync.Map
+}
+
+func (c *httpCache) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return next.RoundTrip(r)
+	default:
+		res, err := next.RoundTrip(r)
+		if err == nil && res.StatusCode < http.StatusBadRequest {
+			c.invalidate(r, res)
+		}
+		return res, err
+	}
+
+	reqCC := requestCacheControl(r.Header)
+	if _, ok := reqCC["no-store"]; ok {
+		return next.RoundTrip(r)
+	}
+	key := cacheKey(r)
+	e := c.load(key, r)
+	if e == nil {
+		if _, ok := reqCC["only-if-cached"]; ok {
+			return &http.Response{
+				Status:     "504 Gateway Timeout",
+				StatusCode: http.StatusGatewayTimeout,
+				Proto:      "HTTP/1.1",
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{},
+				Body:       http.NoBody,
+				Request:    r,
+			}, nil
+		}
+		return c.fetch(next, r, key)
+	}
+
+	now := c.o.Now()
+	resCC := parseCacheControl(e.Header)
+	age := e.age(now)
+	lifetime := e.lifetime(c.o.Shared)
+	if c.fresh(reqCC, resCC, age, lifetime) {
+		return e.response(r, age), nil
+	}
+	if _, ok := reqCC["only-if-cached"]; ok {
+		return e.response(r, age), nil
+	}
+
+	staleness := age - lifetime
+	_, reqNoCache := reqCC["no-cache"]
+	if d, ok := cacheControlSeconds(resCC, "stale-while-revalidate"); ok && staleness <= d && !reqNoCache && !c.mustRevalidate(resCC) {
+		res := e.response(r, age)
+		if _, loaded := c.revalidating.LoadOrStore(key, true); !loaded {
+			rr := r.Clone(detachedContext{r.Context()})
+			go func() {
+				defer c.revalidating.Delete(key)
+				if res, err := c.revalidate(next, rr, key, e); err == nil {
+					_, _ = io.Copy(io.Discard, res.Body)
+					_ = res.Body.Close()
+				}
+			}()
+		}
+		return res, nil
+	}
+
+	res, err := c.revalidate(next, r, key, e)
+	if (err != nil || res.StatusCode >= http.StatusInternalServerError) && c.staleIfError(reqCC, resCC, staleness) {
+		if res != nil {
+			_ = res.Body.Close()
+		}
+		return e.response(r, age), nil
+	}
+	return res, err
+}
+
+func (c *httpCache) fresh(reqCC, resCC map[string]string, age, lifetime time.Duration) bool {
+	if _, ok := resCC["no-cache"]; ok {
+		return false
+	}
+	if _, ok := reqCC["no-cache"]; ok {
+		return false
+	}
+	if d, ok := cacheControlSeconds(reqCC, "max-age"); ok && age > d {
+		return false
+	}
+	if d, ok := cacheControlSeconds(reqCC, "min-fresh"); ok && lifetime-age < d {
+		return false
+	}
+	if age < lifetime {
+		return true
+	}
+	if c.mustRevalidate(resCC) {
+		return false
+	}
+	if v, ok := reqCC["max-stale"]; ok {
+		if v == "" {
+			return true
+		}
+		d, ok := cacheControlSeconds(reqCC, "max-stale")
+		return ok && age-lifetime <= d
+	}
+	return false
+}
+
+func (c *httpCache) mustRevalidate(resCC map[string]string) bool {
+	if _, ok := resCC["must-revalidate"]; ok {
+		return true
+	}
+	_, ok := resCC["proxy-revalidate"]
+	return ok && c.o.Shared
+}
+
+func (c *httpCache) staleIfError(reqCC, resCC map[string]string, staleness time.Duration) bool {
+	if c.mustRevalidate(resCC) {
+		return false
+	}
+	if d, ok := cacheControlSeconds(reqCC, "stale-if-error"); ok {
+		return staleness <= d
+	}
+	d, ok := cacheControlSeconds(resCC, "stale-if-error")
+	return ok && staleness <= d
+}
+
+func (c *httpCache) fetch(next http.RoundTripper, r *http.Request, key string) (*http.Response, error) {
+	requestTime := c.o.Now()
+	res, err := next.RoundTrip(r)
+	if err != nil {
+		return res, err
+	}
+	c.store(r, key, res, requestTime)
+	return res, nil
+}
+
+// revalidate send conditional request, merge stored response when not modified
+func (c *httpCache) revalidate(next http.RoundTripper, r *http.Request, key string, e *cacheEntry) (*http.Response, error) {
+	if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+		// conditional request from caller, let caller handle 304
+		return c.fetch(next, r, key)
+	}
+	rr := r.Clone(r.Context())
+	if v := e.Header.Get("ETag"); v != "" {
+		rr.Header.Set("If-None-Match", v)
+	}
+	if v := e.Header.Get("Last-Modified"); v != "" {
+		rr.Header.Set("If-Modified-Since", v)
+	}
+	requestTime := c.o.Now()
+	res, err := next.RoundTrip(rr)
+	if err != nil {
+		return res, err
+	}
+	if res.StatusCode != http.StatusNotModified {
+		c.store(r, key, res, requestTime)
+		return res, nil
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+
+	for k, v := range res.Header {
+		if k != "Content-Length" {
+			e.Header[k] = v
+		}
+	}
+	e.RequestTime = requestTime
+	e.ResponseTime = c.o.Now()
+	c.save(key, e)
+	return e.response(r, e.age(e.ResponseTime)), nil
+}
+
+// store save response when body fully read
+func (c *httpCache) store(r *http.Request, key string, res *http.Response, requestTime time.Time) {
+	if !c.storable(r, res) {
+		return
+	}
+	e := &cacheEntry{
+		StatusCode:   res.StatusCode,
+		Header:       res.Header.Clone(),
+		Vary:         http.Header{},
+		RequestTime:  requestTime,
+		ResponseTime: c.o.Now(),
+	}
+	for _, v := range res.Header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+				e.Vary[name] = r.Header.Values(name)
+			}
+		}
+	}
+	res.Body = &cacheBody{ReadCloser: res.Body, done: func(body []byte) {
+		e.Body = body
+		c.save(key, e)
+	}}
+}
+
+func (c *httpCache) storable(r *http.Request, res *http.Response) bool {
+	if r.Header.Get("Range") != "" {
+		return false
+	}
+	reqCC := requestCacheControl(r.Header)
+	resCC := parseCacheControl(res.Header)
+	if _, ok := reqCC["no-store"]; ok {
+		return false
+	}
+	if _, ok := resCC["no-store"]; ok {
+		return false
+	}
+	_, public := resCC["public"]
+	_, sMaxAge := resCC["s-maxage"]
+	if c.o.Shared {
+		if _, ok := resCC["private"]; ok {
+			return false
+		}
+		if _, ok := resCC["must-revalidate"]; r.Header.Get("Authorization") != "" && !ok && !public && !sMaxAge {
+			return false
+		}
+	}
+	for _, v := range res.Header.Values("Vary") {
+		if strings.TrimSpace(v) == "*" {
+			return false
+		}
+	}
+	switch res.StatusCode {
+	case http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	_, maxAge := resCC["max-age"]
+	if maxAge || public || (sMaxAge && c.o.Shared) || res.Header.Get("Expires") != "" {
+		return true
+	}
+	return heuristicCacheable(res.StatusCode) && (res.Header.Get("ETag") != "" || res.Header.Get("Last-Modified") != "")
+}
+
+func (c *httpCache) load(key string, r *http.Request) *cacheEntry {
+	b, ok := c.o.Storage.Get(key)
+	if !ok {
+		return nil
+	}
+	e := &cacheEntry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil
+	}
+	for k, v := range e.Vary {
+		if strings.Join(r.Header.Values(k), ",") != strings.Join(v, ",") {
+			return nil
+		}
+	}
+	return e
+}
+
+func (c *httpCache) save(key string, e *cacheEntry) {
+	if b, err := json.Marshal(e); err == nil {
+		c.o.Storage.Set(key, b)
+	}
+}
+
+// invalidate target uri, Location and Content-Location of unsafe request
+func (c *httpCache) invalidate(r *http.Request, res *http.Response) {
+	c.o.Storage.Delete(http.MethodGet + " " + r.URL.String())
+	for _, h := range []string{"Location", "Content-Location"} {
+		v := res.Header.Get(h)
+		if v == "" {
+			continue
+		}
+		u, err := r.URL.Parse(v)
+		if err == nil && u.Host == r.URL.Host {
+			c.o.Storage.Delete(http.MethodGet + " " + u.String())
+		}
+	}
+}
+
+func cacheKey(r *http.Request) string {
+	return r.Method + " " + r.URL.String()
+}
+
+func (e *cacheEntry) date() time.Time {
+	if t, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return t
+	}
+	return e.ResponseTime
+}
+
+// age current age of entry, RFC 9111 4.2.3
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	apparentAge := e.ResponseTime.Sub(e.date())
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	var ageValue time.Duration
+	if v, err := strconv.Atoi(e.Header.Get("Age")); err == nil && v > 0 {
+		ageValue = time.Duration(v) * time.Second
+	}
+	correctedAge := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	if apparentAge > correctedAge {
+		correctedAge = apparentAge
+	}
+	return correctedAge + now.Sub(e.ResponseTime)
+}
+
+// lifetime freshness lifetime of entry, RFC 9111 4.2.1
+func (e *cacheEntry) lifetime(shared bool) time.Duration {
+	cc := parseCacheControl(e.Header)
+	if shared {
+		if d, ok := cacheControlSeconds(cc, "s-maxage"); ok {
+			return d
+		}
+	}
+	if d, ok := cacheControlSeconds(cc, "max-age"); ok {
+		return d
+	}
+	if v := e.Header.Get("Expires"); v != "" {
+		t, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		return t.Sub(e.date())
+	}
+	if lm, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && heuristicCacheable(e.StatusCode) {
+		if d := e.date().Sub(lm); d > 0 {
+			return d / 10 //nolint:gomnd
+		}
+	}
+	return 0
+}
+
+func (e *cacheEntry) response(r *http.Request, age time.Duration) *http.Response {
+	h := e.Header.Clone()
+	h.Set("Age", strconv.Itoa(int(age/time.Second)))
+	h.Set(HeaderFromCache, "1")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       r,
+	}
+}
+
+// heuristicCacheable status codes cacheable by default, RFC 9110 15.1
+func heuristicCacheable(code int) bool {
+	switch code {
+	case 200, 203, 204, 300, 301, 308, 404, 405, 410, 414, 501:
+		return true
+	}
+	return false
+}
+
+// parseCacheControl directives of Cache-Control, names are lower cased and values unquoted
+func parseCacheControl(h http.Header) map[string]string {
+	cc := map[string]string{}
+	for _, line := range h.Values("Cache-Control") {
+		for _, v := range strings.Split(line, ",") {
+			k, val, _ := cutString(strings.TrimSpace(v), "=")
+			if k == "" {
+				continue
+			}
+			cc[strings.ToLower(k)] = strings.Trim(val, `"`)
+		}
+	}
+	return cc
+}
+
+// requestCacheControl Cache-Control of request, Pragma: no-cache is no-cache when Cache-Control absent
+func requestCacheControl(h http.Header) map[string]string {
+	cc := parseCacheControl(h)
+	if len(cc) == 0 && strings.Contains(h.Get("Pragma"), "no-cache") {
+		cc["no-cache"] = ""
+	}
+	return cc
+}
+
+func cacheControlSeconds(cc map[string]string, name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// cacheBody call done with whole body when read to EOF
+type cacheBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func(body []byte)
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF && b.done != nil {
+		b.done(b.buf.Bytes())
+		b.done = nil
+	}
+	return n, err
+}
+
+// detachedContext keep values of parent but never canceled
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
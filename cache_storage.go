@@ -0,0 +1,116 @@
+package req
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheStorage storage of CacheHook, implementations must be safe for concurrent use
+type CacheStorage interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+type memoryCacheItem struct {
+	key   string
+	value []byte
+}
+
+type memoryCache struct {
+	mu    sync.Mutex
+	max   int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// NewMemoryCache in-memory LRU CacheStorage, maxEntries <= 0 means unlimited
+func NewMemoryCache(maxEntries int) CacheStorage {
+	return &memoryCache{max: maxEntries, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*memoryCacheItem).value, true
+}
+
+func (c *memoryCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		e.Value.(*memoryCacheItem).value = value
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&memoryCacheItem{key: key, value: value})
+	if c.max > 0 && c.ll.Len() > c.max {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*memoryCacheItem).key)
+	}
+}
+
+func (c *memoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+type diskCache struct {
+	dir string
+}
+
+// NewDiskCache CacheStorage store entries as files in dir, dir is created on demand
+func NewDiskCache(dir string) CacheStorage {
+	return &diskCache{dir: dir}
+}
+
+func (c *diskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func (c *diskCache) Get(key string) ([]byte, bool) {
+	b, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+func (c *diskCache) Set(key string, value []byte) {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(value)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+}
+
+func (c *diskCache) Delete(key string) {
+	_ = os.Remove(c.path(key))
+}
@@ -0,0 +1,192 @@
+package req_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestCacheHook(t *testing.T) {
+	var calls int32
+	var failing int32
+	var mu sync.Mutex
+	now := time.Now()
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Date", clock().UTC().Format(http.TimeFormat))
+		if r.Method != http.MethodGet {
+			return
+		}
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		switch r.URL.Path {
+		case "/etag":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/last-modified":
+			w.Header().Set("Last-Modified", clock().Add(-100*time.Second).UTC().Format(http.TimeFormat))
+			if r.Header.Get("If-Modified-Since") != "" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/swr":
+			w.Header().Set("Cache-Control", "max-age=1, stale-while-revalidate=60")
+		case "/sie":
+			w.Header().Set("Cache-Control", "max-age=1, stale-if-error=60")
+		}
+		_, _ = fmt.Fprintf(w, "%s %d %s", r.URL.Path, n, r.Header.Get("Accept"))
+	}))
+	defer server.Close()
+
+	client := req.Request{BaseURL: server.URL}.WithHook(req.CacheHook(&req.CacheOptions{Now: clock}))
+	fetch := func(path string, h http.Header) (string, bool) {
+		out, res, err := client.With(req.Request{URL: path, Header: h}).FetchString()
+		assert.NoError(t, err)
+		return out, res.Header.Get(req.HeaderFromCache) == "1"
+	}
+	count := func() int32 {
+		return atomic.SwapInt32(&calls, 0)
+	}
+
+	// fresh then revalidate
+	out, cached := fetch("/etag", nil)
+	assert.Equal(t, "/etag 1 ", out)
+	assert.False(t, cached)
+	out, cached = fetch("/etag", nil)
+	assert.Equal(t, "/etag 1 ", out)
+	assert.True(t, cached)
+	assert.EqualValues(t, 1, count())
+	advance(time.Minute)
+	out, cached = fetch("/etag", nil)
+	assert.Equal(t, "/etag 1 ", out)
+	assert.True(t, cached)
+	assert.EqualValues(t, 1, count())
+	fetch("/etag", nil)
+	assert.EqualValues(t, 0, count())
+	// request no-cache
+	fetch("/etag", http.Header{"Cache-Control": []string{"no-cache"}})
+	assert.EqualValues(t, 1, count())
+
+	// vary
+	out, _ = fetch("/vary", http.Header{"Accept": []string{"a"}})
+	assert.Equal(t, "/vary 1 a", out)
+	out, cached = fetch("/vary", http.Header{"Accept": []string{"a"}})
+	assert.Equal(t, "/vary 1 a", out)
+	assert.True(t, cached)
+	out, cached = fetch("/vary", http.Header{"Accept": []string{"b"}})
+	assert.Equal(t, "/vary 2 b", out)
+	assert.False(t, cached)
+	count()
+
+	// no-store
+	fetch("/no-store", nil)
+	_, cached = fetch("/no-store", nil)
+	assert.False(t, cached)
+	assert.EqualValues(t, 2, count())
+
+	// heuristic freshness of Last-Modified, 10s
+	fetch("/last-modified", nil)
+	_, cached = fetch("/last-modified", nil)
+	assert.True(t, cached)
+	advance(20 * time.Second)
+	out, cached = fetch("/last-modified", nil)
+	assert.Equal(t, "/last-modified 1 ", out)
+	assert.True(t, cached)
+	assert.EqualValues(t, 2, count())
+
+	// stale-while-revalidate
+	fetch("/swr", nil)
+	advance(10 * time.Second)
+	out, cached = fetch("/swr", nil)
+	assert.Equal(t, "/swr 1 ", out)
+	assert.True(t, cached)
+	assert.Eventually(t, func() bool {
+		out, _ = fetch("/swr", nil)
+		return out == "/swr 2 "
+	}, time.Second, 10*time.Millisecond)
+	count()
+
+	// stale-if-error
+	fetch("/sie", nil)
+	advance(10 * time.Second)
+	atomic.StoreInt32(&failing, 1)
+	out, cached = fetch("/sie", nil)
+	assert.Equal(t, "/sie 1 ", out)
+	assert.True(t, cached)
+	advance(time.Minute)
+	_, res, err := client.With(req.Request{URL: "/sie"}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	atomic.StoreInt32(&failing, 0)
+	count()
+
+	// unsafe method invalidate
+	fetch("/etag", nil)
+	_, cached = fetch("/etag", nil)
+	assert.True(t, cached)
+	_, err = client.With(req.Request{URL: "/etag", Method: http.MethodPost}).Do()
+	assert.NoError(t, err)
+	_, cached = fetch("/etag", nil)
+	assert.False(t, cached)
+
+	// only-if-cached
+	_, res, err = client.With(req.Request{URL: "/none", Header: http.Header{"Cache-Control": []string{"only-if-cached"}}}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, res.StatusCode)
+}
+
+func TestCacheStorage(t *testing.T) {
+	for _, s := range []req.CacheStorage{req.NewMemoryCache(2), req.NewDiskCache(t.TempDir())} {
+		_, ok := s.Get("a")
+		assert.False(t, ok)
+		s.Set("a", []byte("1"))
+		s.Set("b", []byte("2"))
+		v, ok := s.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, "1", string(v))
+		s.Set("a", []byte("3"))
+		v, _ = s.Get("a")
+		assert.Equal(t, "3", string(v))
+		s.Delete("a")
+		_, ok = s.Get("a")
+		assert.False(t, ok)
+	}
+
+	lru := req.NewMemoryCache(2)
+	lru.Set("a", nil)
+	lru.Set("b", nil)
+	lru.Get("a")
+	lru.Set("c", nil)
+	_, ok := lru.Get("b")
+	assert.False(t, ok)
+	_, ok = lru.Get("a")
+	assert.True(t, ok)
+}
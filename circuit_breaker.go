@@ -0,0 +1,183 @@
+package req
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen returned when request is short-circuited by CircuitBreakerHook
+var ErrCircuitOpen = errors.New("req: circuit open")
+
+// CircuitState state of circuit
+type CircuitState int
+
+// Circuit states
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerOptions options for CircuitBreakerHook
+type CircuitBreakerOptions struct {
+	Key              func(r *http.Request) string            // Key of circuit, default HostKey
+	FailureThreshold int                                     // FailureThreshold consecutive failures to open, default 5
+	OpenTimeout      time.Duration                           // OpenTimeout before half-open, default 30s
+	HalfOpenRequests int                                     // HalfOpenRequests allowed trial requests in half-open, default 1
+	FailureStatus    []int                                   // FailureStatus status code count as failure, default >= 500
+	OnStateChange    func(key string, from, to CircuitState) // OnStateChange called after state changed
+	Now              func() time.Time                        // Now default time.Now
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	inflight int
+}
+
+// CircuitBreakerHook short-circuit request with ErrCircuitOpen when failures reach threshold,
+// failures are transport errors and FailureStatus responses.
+func CircuitBreakerHook(o *CircuitBreakerOptions) Hook {
+	if o == nil {
+		o = &CircuitBreakerOptions{}
+	}
+	cb := &circuitBreaker{o: *o, circuits: map[string]*circuit{}}
+	if cb.o.Key == nil {
+		cb.o.Key = HostKey
+	}
+	if cb.o.FailureThreshold <= 0 {
+		cb.o.FailureThreshold = 5 //nolint:gomnd
+	}
+	if cb.o.OpenTimeout <= 0 {
+		cb.o.OpenTimeout = 30 * time.Second //nolint:gomnd
+	}
+	if cb.o.HalfOpenRequests <= 0 {
+		cb.o.HalfOpenRequests = 1
+	}
+	if cb.o.Now == nil {
+		cb.o.Now = time.Now
+	}
+	return Hook{
+		Name:  "CircuitBreaker",
+		Order: -30,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				key := cb.o.Key(r)
+				if err := cb.allow(key); err != nil {
+					return nil, err
+				}
+				res, err := next.RoundTrip(r)
+				if err != nil && r.Context().Err() != nil {
+					// canceled by caller, not a failure of upstream
+					cb.release(key)
+					return res, err
+				}
+				cb.record(key, err != nil || cb.isFailureStatus(res.StatusCode))
+				return res, err
+			})
+		},
+	}
+}
+
+type circuitBreaker struct {
+	o        CircuitBreakerOptions
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+func (cb *circuitBreaker) isFailureStatus(code int) bool {
+	if cb.o.FailureStatus == nil {
+		return code >= http.StatusInternalServerError
+	}
+	for _, v := range cb.o.FailureStatus {
+		if v == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (cb *circuitBreaker) allow(key string) error {
+	cb.mu.Lock()
+	c, ok := cb.circuits[key]
+	if !ok {
+		c = &circuit{}
+		cb.circuits[key] = c
+	}
+	from := c.state
+	if c.state == CircuitOpen && cb.o.Now().Sub(c.openedAt) >= cb.o.OpenTimeout {
+		c.state = CircuitHalfOpen
+		c.inflight = 0
+	}
+	var err error
+	switch {
+	case c.state == CircuitOpen:
+		err = errors.Wrap(ErrCircuitOpen, key)
+	case c.state == CircuitHalfOpen && c.inflight >= cb.o.HalfOpenRequests:
+		err = errors.Wrap(ErrCircuitOpen, key)
+	case c.state == CircuitHalfOpen:
+		c.inflight++
+	}
+	to := c.state
+	cb.mu.Unlock()
+	cb.changed(key, from, to)
+	return err
+}
+
+func (cb *circuitBreaker) release(key string) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if c := cb.circuits[key]; c.state == CircuitHalfOpen && c.inflight > 0 {
+		c.inflight--
+	}
+}
+
+func (cb *circuitBreaker) record(key string, failed bool) {
+	cb.mu.Lock()
+	c := cb.circuits[key]
+	from := c.state
+	switch {
+	case c.state == CircuitOpen:
+		// result of request started before opened, keep cool-down
+	case !failed:
+		c.state = CircuitClosed
+		c.failures = 0
+	case c.state == CircuitHalfOpen:
+		c.state = CircuitOpen
+		c.openedAt = cb.o.Now()
+	case c.state == CircuitClosed:
+		c.failures++
+		if c.failures >= cb.o.FailureThreshold {
+			c.state = CircuitOpen
+			c.openedAt = cb.o.Now()
+		}
+	}
+	if c.state != CircuitHalfOpen {
+		c.inflight = 0
+	}
+	to := c.state
+	cb.mu.Unlock()
+	cb.changed(key, from, to)
+}
+
+func (cb *circuitBreaker) changed(key string, from, to CircuitState) {
+	if from != to && cb.o.OnStateChange != nil {
+		cb.o.OnStateChange(key, from, to)
+	}
+}
@@ -0,0 +1,121 @@
+package req_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestCircuitBreakerHook(t *testing.T) {
+	now := time.Now()
+	status := http.StatusInternalServerError
+	calls := 0
+	var changes []string
+	client := req.Request{BaseURL: "http://wener.me"}.WithHook(
+		req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+			calls++
+			if status == 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+		})),
+		req.CircuitBreakerHook(&req.CircuitBreakerOptions{
+			FailureThreshold: 2,
+			OpenTimeout:      time.Minute,
+			Now:              func() time.Time { return now },
+			OnStateChange: func(key string, from, to req.CircuitState) {
+				changes = append(changes, key+" "+from.String()+" -> "+to.String())
+			},
+		}),
+		req.DebugHook(&req.DebugOptions{Disable: true}),
+	)
+	do := func() error {
+		_, err := client.Do()
+		return err
+	}
+
+	assert.NoError(t, do())
+	status = 0
+	assert.Error(t, do())
+	assert.Equal(t, 2, calls)
+	assert.True(t, errors.Is(do(), req.ErrCircuitOpen))
+	assert.Equal(t, 2, calls)
+
+	now = now.Add(time.Minute)
+	status = http.StatusOK
+	assert.NoError(t, do())
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []string{
+		"wener.me closed -> open",
+		"wener.me open -> half-open",
+		"wener.me half-open -> closed",
+	}, changes)
+
+	// half-open failure open again
+	status = http.StatusBadGateway
+	assert.NoError(t, do())
+	assert.NoError(t, do())
+	now = now.Add(time.Minute)
+	assert.NoError(t, do())
+	assert.True(t, errors.Is(do(), req.ErrCircuitOpen))
+	assert.Equal(t, 6, calls)
+}
+
+func TestCircuitBreakerStatus(t *testing.T) {
+	client := req.Request{BaseURL: "http://wener.me"}.WithHook(
+		req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+		})),
+		req.CircuitBreakerHook(&req.CircuitBreakerOptions{
+			FailureThreshold: 1,
+			FailureStatus:    []int{http.StatusTooManyRequests},
+			Key: func(r *http.Request) string {
+				return r.URL.Path
+			},
+		}),
+	)
+	_, err := client.With(req.Request{URL: "/a"}).Do()
+	assert.NoError(t, err)
+	_, err = client.With(req.Request{URL: "/a"}).Do()
+	assert.True(t, errors.Is(err, req.ErrCircuitOpen))
+	_, err = client.With(req.Request{URL: "/b"}).Do()
+	assert.NoError(t, err)
+}
+
+func TestCircuitBreakerLateSuccess(t *testing.T) {
+	slow := make(chan struct{})
+	started := make(chan struct{})
+	client := req.Request{BaseURL: "http://wener.me"}.WithHook(
+		req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Path == "/slow" {
+				close(started)
+				<-slow
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+			}
+			return nil, io.ErrUnexpectedEOF
+		})),
+		req.CircuitBreakerHook(&req.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute}),
+	)
+	done := make(chan error)
+	go func() {
+		_, err := client.With(req.Request{URL: "/slow"}).Do()
+		done <- err
+	}()
+	<-started
+	_, err := client.Do()
+	assert.False(t, errors.Is(err, req.ErrCircuitOpen))
+	_, err = client.Do()
+	assert.True(t, errors.Is(err, req.ErrCircuitOpen))
+
+	// success started before opened must not close the circuit
+	close(slow)
+	assert.NoError(t, <-done)
+	_, err = client.Do()
+	assert.True(t, errors.Is(err, req.ErrCircuitOpen))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/wenerme/go-req"
+)
+
+// item separators, longer first for same position
+var itemSeparators = []string{":=@", "==", ":=", "=@", "@", "=", ":"}
+
+type item struct {
+	Key   string
+	Sep   string
+	Value string
+}
+
+// parseItem split request item by first unescaped separator, backslash escapes separator chars in key
+func parseItem(s string) (item, error) {
+	var key strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			key.WriteByte(s[i])
+			continue
+		}
+		for _, sep := range itemSeparators {
+			if strings.HasPrefix(s[i:], sep) {
+				return item{Key: key.String(), Sep: sep, Value: s[i+len(sep):]}, nil
+			}
+		}
+		key.WriteByte(s[i])
+	}
+	return item{}, errors.Errorf("invalid request item %q", s)
+}
+
+var (
+	methodPattern = regexp.MustCompile(`^[A-Z]+$`)
+	portPattern   = regexp.MustCompile(`^[0-9]+(/|$)`)
+	knownMethods  = map[string]bool{
+		http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true,
+		http.MethodDelete: true, http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+	}
+)
+
+// isMethod report whether s is a known method in any case or an uppercase custom method
+func isMethod(s string) bool {
+	return knownMethods[strings.ToUpper(s)] || methodPattern.MatchString(s)
+}
+
+// looksLikeURL report whether s is an url rather than a request item, e.g. localhost:8080 or example.com/a?b=1
+func looksLikeURL(s string) bool {
+	it, err := parseItem(s)
+	switch {
+	case err != nil, strings.HasPrefix(s, ":"), strings.Contains(s, "://"):
+		return true
+	case it.Sep == ":" && portPattern.MatchString(it.Value):
+		return true
+	}
+	return strings.ContainsAny(it.Key, "/?")
+}
+
+// normalizeURL expand :port/path shorthand to localhost and add default scheme
+func normalizeURL(s string, scheme string) string {
+	switch {
+	case strings.HasPrefix(s, "://"):
+		s = s[3:]
+	case s == ":" || strings.HasPrefix(s, ":/"):
+		s = "localhost" + s[1:]
+	case strings.HasPrefix(s, ":"):
+		s = "localhost" + s
+	case strings.Contains(s, "://"):
+		return s
+	}
+	return scheme + "://" + s
+}
+
+// buildRequest build req.Request from positional arguments - [METHOD] URL [ITEM...]
+func buildRequest(o *options, args []string) (req.Request, error) {
+	r := req.Request{}
+	if len(args) == 0 {
+		return r, errors.New("missing url")
+	}
+	if len(args) > 1 && isMethod(args[0]) && looksLikeURL(args[1]) {
+		r.Method = strings.ToUpper(args[0])
+		args = args[1:]
+	}
+	r.URL = normalizeURL(args[0], o.scheme)
+
+	var (
+		header   = http.Header{}
+		removed  []string
+		query    = url.Values{}
+		fields   = map[string]interface{}{}
+		hasFile  bool
+		hasJSON  bool
+		hasField bool
+	)
+	for _, s := range args[1:] {
+		it, err := parseItem(s)
+		if err != nil {
+			return r, err
+		}
+		switch it.Sep {
+		case ":":
+			if it.Value == "" {
+				removed = append(removed, it.Key)
+				continue
+			}
+			header.Add(it.Key, it.Value)
+		case "==":
+			query.Add(it.Key, it.Value)
+		case "=", "=@":
+			v := it.Value
+			if it.Sep == "=@" {
+				b, err := os.ReadFile(v)
+				if err != nil {
+					return r, errors.Wrapf(err, "read field %s", it.Key)
+				}
+				v = string(b)
+			}
+			fields[it.Key] = v
+			hasField = true
+		case ":=", ":=@":
+			if o.form || o.multipart {
+				return r, errors.Errorf("raw JSON field %s is not supported for form", it.Key)
+			}
+			b := []byte(it.Value)
+			if it.Sep == ":=@" {
+				if b, err = os.ReadFile(it.Value); err != nil {
+					return r, errors.Wrapf(err, "read field %s", it.Key)
+				}
+			}
+			var v interface{}
+			if err = json.Unmarshal(b, &v); err != nil {
+				return r, errors.Wrapf(err, "invalid JSON field %s", it.Key)
+			}
+			fields[it.Key] = v
+			hasJSON = true
+			hasField = true
+		case "@":
+			if o.json {
+				return r, errors.Errorf("file field %s is not supported for --json", it.Key)
+			}
+			path, typ := it.Value, ""
+			if i := strings.Index(path, ";type="); i >= 0 {
+				path, typ = path[:i], path[i+len(";type="):]
+			}
+			fields[it.Key] = &req.MultipartFile{Path: path, Filename: filepath.Base(path), ContentType: typ}
+			hasFile = true
+			hasField = true
+		}
+	}
+
+	if hasFile && hasJSON {
+		return r, errors.New("raw JSON field is not supported with file field")
+	}
+	if len(query) > 0 {
+		r.Query = query
+	}
+	if len(header) > 0 {
+		r.Header = header
+	}
+	if hasField {
+		switch {
+		case o.multipart || hasFile:
+			r.Body = fields
+			r = r.WithHook(req.MultipartFormEncode)
+		case o.form:
+			form := url.Values{}
+			for k, v := range fields {
+				form.Set(k, v.(string))
+			}
+			r.Body = form
+			r = r.WithHook(req.FormEncode)
+		default:
+			r.Body = fields
+			r = r.WithHook(req.JSONEncode)
+			if header.Get("Accept") == "" {
+				header.Set("Accept", "application/json, */*;q=0.5")
+				r.Header = header
+			}
+		}
+		if r.Method == "" {
+			r.Method = http.MethodPost
+		}
+	}
+	if len(removed) > 0 {
+		r = r.WithHook(req.Hook{
+			Name:  "RemoveHeader",
+			Order: -90,
+			OnRequest: func(r *http.Request) error {
+				for _, k := range removed {
+					r.Header.Del(k)
+				}
+				return nil
+			},
+		})
+	}
+	return r, nil
+}
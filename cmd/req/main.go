@@ -0,0 +1,260 @@
+// Command req is a httpie like command line HTTP client built on go-req
+//
+//	req [flags] [METHOD] URL [ITEM...]
+//
+// Flags can be anywhere in arguments, arguments after -- are not flags.
+//
+// Request items
+//
+//	Header:Value      request header, Header: remove header
+//	name==value       query parameter
+//	name=value        string field
+//	name=@file        string field from file
+//	name:=json        raw JSON field
+//	name:=@file       raw JSON field from file
+//	name@file         file field, name@file;type=mime set content type, imply --multipart unless --json
+//
+// URL :8080/path is short for http://localhost:8080/path, scheme default to http.
+// Method default to POST when fields present, GET otherwise.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wenerme/go-req"
+)
+
+type options struct {
+	json        bool
+	form        bool
+	multipart   bool
+	debug       bool
+	curl        bool
+	session     string
+	pretty      string
+	print       string
+	verbose     bool
+	timeout     time.Duration
+	insecure    bool
+	checkStatus bool
+	scheme      string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func parseFlags(args []string, stderr io.Writer) (*options, []string, error) {
+	o := &options{}
+	fs := flag.NewFlagSet("req", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintln(stderr, "usage: req [flags] [METHOD] URL [ITEM...]")
+		fs.PrintDefaults()
+	}
+	var https bool
+	for _, name := range []string{"j", "json"} {
+		fs.BoolVar(&o.json, name, false, "serialize fields as JSON object (default)")
+	}
+	for _, name := range []string{"f", "form"} {
+		fs.BoolVar(&o.form, name, false, "serialize fields as application/x-www-form-urlencoded")
+	}
+	fs.BoolVar(&o.multipart, "multipart", false, "serialize fields as multipart/form-data")
+	fs.BoolVar(&o.debug, "debug", false, "dump request and response to stderr")
+	fs.BoolVar(&o.curl, "curl", false, "print equivalent curl command without sending request")
+	fs.StringVar(&o.session, "session", "", "session name or file to keep headers and cookies")
+	fs.StringVar(&o.pretty, "pretty", "auto", "output style: all, colors, format, none or auto")
+	for _, name := range []string{"p", "print"} {
+		fs.StringVar(&o.print, name, "", "what to print: H request headers, B request body, h response headers, b response body")
+	}
+	for _, name := range []string{"v", "verbose"} {
+		fs.BoolVar(&o.verbose, name, false, "print request and response, same as --print=HBhb")
+	}
+	fs.DurationVar(&o.timeout, "timeout", 0, "request timeout")
+	for _, name := range []string{"k", "insecure"} {
+		fs.BoolVar(&o.insecure, name, false, "skip TLS certificate verification")
+	}
+	fs.BoolVar(&o.checkStatus, "check-status", false, "exit with 3, 4 or 5 for 3xx, 4xx or 5xx response")
+	fs.BoolVar(&https, "https", false, "default scheme to https")
+	flags, positional := splitFlags(fs, args)
+	if err := fs.Parse(flags); err != nil {
+		return nil, nil, err
+	}
+	if o.form && o.multipart {
+		return nil, nil, errors.New("--form and --multipart are exclusive")
+	}
+	if (o.form || o.multipart) && o.json {
+		return nil, nil, errors.New("--json and --form are exclusive")
+	}
+	o.scheme = "http"
+	if https {
+		o.scheme = "https"
+	}
+	return o, positional, nil
+}
+
+// splitFlags separate flags from positional arguments, flags can be anywhere like httpie, -- ends flags
+func splitFlags(fs *flag.FlagSet, args []string) (flags, positional []string) {
+	for i := 0; i < len(args); i++ {
+		s := args[i]
+		switch {
+		case s == "--":
+			return flags, append(positional, args[i+1:]...)
+		case len(s) < 2 || s[0] != '-':
+			positional = append(positional, s)
+			continue
+		}
+		flags = append(flags, s)
+		name := strings.TrimLeft(s, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := fs.Lookup(name); f != nil && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return flags, positional
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	o, args, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "req:", err)
+		return 2
+	}
+	r, err := buildRequest(o, args)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "req:", err)
+		return 2
+	}
+	if err = configure(o, &r, stderr); err != nil {
+		_, _ = fmt.Fprintln(stderr, "req:", err)
+		return 1
+	}
+
+	if o.curl {
+		cmd, err := r.Curl()
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, "req:", err)
+			return 1
+		}
+		_, _ = fmt.Fprintln(stdout, cmd)
+		return 0
+	}
+
+	if o.timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+		defer cancel()
+		r.Context = ctx
+	}
+	res, err := r.Do()
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "req:", err)
+		return 1
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, "req:", err)
+		return 1
+	}
+	output(o, stdout, res, body)
+
+	if o.checkStatus {
+		switch {
+		case res.StatusCode >= http.StatusInternalServerError:
+			return 5 //nolint:gomnd
+		case res.StatusCode >= http.StatusBadRequest:
+			return 4 //nolint:gomnd
+		case res.StatusCode >= http.StatusMultipleChoices:
+			return 3 //nolint:gomnd
+		}
+	}
+	return 0
+}
+
+// configure add hooks of options to Request
+func configure(o *options, r *req.Request, stderr io.Writer) error {
+	if o.insecure {
+		*r = r.WithHook(req.TLSHook(&req.TLSOptions{InsecureSkipVerify: true}))
+	}
+	if o.debug {
+		*r = r.WithHook(req.DebugHook(&req.DebugOptions{Body: true, Out: stderr}))
+	}
+	if o.session != "" {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return err
+		}
+		path, err := sessionPath(o.session, u.Host)
+		if err != nil {
+			return err
+		}
+		s, err := loadSession(path)
+		if err != nil {
+			return err
+		}
+		*r = r.WithHook(s.hook())
+	}
+	return nil
+}
+
+func output(o *options, stdout io.Writer, res *http.Response, body []byte) {
+	tty := isTerminal(stdout)
+	p := &printer{out: stdout}
+	switch o.pretty {
+	case "all":
+		p.format, p.color = true, true
+	case "colors":
+		p.color = true
+	case "format":
+		p.format = true
+	case "none":
+	default:
+		p.format, p.color = tty, tty
+	}
+	what := o.print
+	switch {
+	case what != "":
+	case o.verbose:
+		what = "HBhb"
+	case tty:
+		what = "hb"
+	default:
+		what = "b"
+	}
+
+	if strings.ContainsRune(what, 'H') {
+		p.requestHead(res.Request)
+	}
+	if strings.ContainsRune(what, 'B') {
+		if re := req.FromContext(res.Request.Context()); re != nil && len(re.RawBody) > 0 {
+			p.body(res.Request.Header.Get("Content-Type"), re.RawBody)
+			_, _ = fmt.Fprintln(stdout)
+		}
+	}
+	if strings.ContainsRune(what, 'h') {
+		p.responseHead(res)
+	}
+	if strings.ContainsRune(what, 'b') {
+		p.body(res.Header.Get("Content-Type"), body)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseItem(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		out item
+	}{
+		{"Authorization:Bearer abc", item{"Authorization", ":", "Bearer abc"}},
+		{"X-Empty:", item{"X-Empty", ":", ""}},
+		{"q==a:b", item{"q", "==", "a:b"}},
+		{"name=x=y", item{"name", "=", "x=y"}},
+		{"age:=3", item{"age", ":=", "3"}},
+		{"tags:=@tags.json", item{"tags", ":=@", "tags.json"}},
+		{"bio=@bio.txt", item{"bio", "=@", "bio.txt"}},
+		{"file@a.png", item{"file", "@", "a.png"}},
+		{`a\:b=c`, item{"a:b", "=", "c"}},
+		{"time==10:00", item{"time", "==", "10:00"}},
+	} {
+		it, err := parseItem(test.in)
+		assert.NoError(t, err, test.in)
+		assert.Equal(t, test.out, it, test.in)
+	}
+	_, err := parseItem("plain")
+	assert.Error(t, err)
+}
+
+func TestParseFlags(t *testing.T) {
+	o, args, err := parseFlags([]string{"POST", ":8080/users", "name=x", "--debug", "--timeout", "1s", "-p=b", "--", "-x=1"}, io.Discard)
+	assert.NoError(t, err)
+	assert.True(t, o.debug)
+	assert.Equal(t, time.Second, o.timeout)
+	assert.Equal(t, "b", o.print)
+	assert.Equal(t, []string{"POST", ":8080/users", "name=x", "-x=1"}, args)
+
+	_, _, err = parseFlags([]string{":8080", "--unknown"}, io.Discard)
+	assert.Error(t, err)
+}
+
+func TestNormalizeURL(t *testing.T) {
+	for in, out := range map[string]string{
+		":8080/users":          "http://localhost:8080/users",
+		":/users":              "http://localhost/users",
+		":":                    "http://localhost",
+		"example.com/a":        "http://example.com/a",
+		"https://example.com/": "https://example.com/",
+		"://example.com":       "http://example.com",
+	} {
+		assert.Equal(t, out, normalizeURL(in, "http"), in)
+	}
+}
+
+func TestBuildRequestMethod(t *testing.T) {
+	for _, test := range []struct {
+		args   []string
+		method string
+		url    string
+	}{
+		{[]string{"localhost", "foo=bar"}, http.MethodPost, "http://localhost"},
+		{[]string{"localhost:8080"}, "", "http://localhost:8080"},
+		{[]string{"get", "example.com"}, http.MethodGet, "http://example.com"},
+		{[]string{"PURGE", "localhost:8080/a"}, "PURGE", "http://localhost:8080/a"},
+		{[]string{"GET", "example.com/a?b=1"}, http.MethodGet, "http://example.com/a?b=1"},
+		{[]string{"DELETE", "foo=bar"}, http.MethodPost, "http://DELETE"},
+	} {
+		r, err := buildRequest(&options{scheme: "http"}, test.args)
+		assert.NoError(t, err, test.args)
+		assert.Equal(t, test.method, r.Method, test.args)
+		assert.Equal(t, test.url, r.URL, test.args)
+	}
+	// lowercase custom method is an url
+	_, err := buildRequest(&options{scheme: "http"}, []string{"purge", "example.com"})
+	assert.Error(t, err)
+}
+
+type echo struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   string
+}
+
+func newEchoServer(t *testing.T) (*httptest.Server, *echo) {
+	last := &echo{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		*last = echo{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header, Body: string(b)}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "s1", Path: "/"})
+		case "/fail":
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"n":1}`))
+	}))
+	t.Cleanup(s.Close)
+	return s, last
+}
+
+func runArgs(args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunJSON(t *testing.T) {
+	s, last := newEchoServer(t)
+	port := s.URL[strings.LastIndex(s.URL, ":"):]
+
+	code, out, errOut := runArgs("--pretty=none", "POST", port+"/users", "name=x", "age:=3", "Authorization:Bearer abc", "q==1")
+	assert.Equal(t, 0, code, errOut)
+	assert.Equal(t, "{\"ok\":true,\"n\":1}\n", out)
+	assert.Equal(t, http.MethodPost, last.Method)
+	assert.Equal(t, "/users", last.Path)
+	assert.Equal(t, "q=1", last.Query)
+	assert.Equal(t, "Bearer abc", last.Header.Get("Authorization"))
+	assert.Contains(t, last.Header.Get("Content-Type"), "application/json")
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(last.Body), &body))
+	assert.Equal(t, map[string]interface{}{"name": "x", "age": float64(3)}, body)
+
+	// method default to GET without fields
+	code, _, _ = runArgs(s.URL + "/get")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, http.MethodGet, last.Method)
+
+	// method default to POST with fields
+	code, _, _ = runArgs(s.URL, "a=1")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, http.MethodPost, last.Method)
+}
+
+func TestRunForm(t *testing.T) {
+	s, last := newEchoServer(t)
+
+	code, _, errOut := runArgs("--form", s.URL, "name=x", "city=a b")
+	assert.Equal(t, 0, code, errOut)
+	assert.Equal(t, "application/x-www-form-urlencoded", last.Header.Get("Content-Type"))
+	assert.Equal(t, "city=a+b&name=x", last.Body)
+
+	code, _, errOut = runArgs("--form", s.URL, "age:=3")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, errOut, "raw JSON")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("hello"), 0o600))
+	code, _, errOut = runArgs("--form", s.URL, "name=x", "file@"+file+";type=text/plain")
+	assert.Equal(t, 0, code, errOut)
+	assert.Contains(t, last.Header.Get("Content-Type"), "multipart/form-data; boundary=")
+	assert.Contains(t, last.Body, `name="file"; filename="a.txt"`)
+	assert.Contains(t, last.Body, "Content-Type: text/plain")
+	assert.Contains(t, last.Body, "hello")
+
+	// file field imply multipart
+	code, _, errOut = runArgs(s.URL, "name=x", "file@"+file)
+	assert.Equal(t, 0, code, errOut)
+	assert.Contains(t, last.Header.Get("Content-Type"), "multipart/form-data; boundary=")
+	assert.Contains(t, last.Body, `name="file"; filename="a.txt"`)
+
+	for _, args := range [][]string{
+		{"--json", s.URL, "file@" + file},
+		{s.URL, "age:=3", "file@" + file},
+	} {
+		code, _, errOut = runArgs(args...)
+		assert.Equal(t, 2, code, args)
+		assert.Contains(t, errOut, "not supported", args)
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	s, _ := newEchoServer(t)
+
+	code, out, _ := runArgs("--pretty=format", "-p", "hb", s.URL+"/fail")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "HTTP/1.1 404 Not Found\n")
+	assert.Contains(t, out, "Content-Type: application/json\n")
+	assert.Contains(t, out, "{\n  \"ok\": true,\n  \"n\": 1\n}\n")
+
+	code, out, _ = runArgs("--pretty=none", "-v", s.URL+"/v", "a=1")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, "POST /v HTTP/1.1\n")
+	assert.Contains(t, out, `{"a":"1"}`)
+
+	code, _, errOut := runArgs("--debug", s.URL+"/debug")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, errOut, "GET /debug HTTP/1.1")
+	assert.Contains(t, errOut, "HTTP/1.1 200 OK")
+
+	code, _, _ = runArgs("--check-status", s.URL+"/fail")
+	assert.Equal(t, 4, code)
+
+	code, out, _ = runArgs("--pretty=all", s.URL)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, out, colorKey+`"ok"`+colorReset)
+	assert.Contains(t, out, colorLit+"true"+colorReset)
+	assert.Contains(t, out, colorNumber+"1"+colorReset)
+}
+
+func TestRunCurl(t *testing.T) {
+	code, out, errOut := runArgs("--curl", "PUT", "example.com/users", "name=x", "X-Token:t")
+	assert.Equal(t, 0, code, errOut)
+	assert.Equal(t, `curl -X PUT -H 'Accept: application/json, */*;q=0.5' -H 'Content-Type: application/json;charset=UTF-8' -H 'X-Token: t' --data-raw '{"name":"x"}' http://example.com/users`+"\n", out)
+}
+
+func TestRunSession(t *testing.T) {
+	s, last := newEchoServer(t)
+	file := filepath.Join(t.TempDir(), "session.json")
+
+	code, _, errOut := runArgs("--session", file, s.URL+"/login", "X-Token:t")
+	assert.Equal(t, 0, code, errOut)
+
+	code, _, errOut = runArgs("--session", file, s.URL+"/me")
+	assert.Equal(t, 0, code, errOut)
+	assert.Equal(t, "t", last.Header.Get("X-Token"))
+	assert.Equal(t, "sid=s1", last.Header.Get("Cookie"))
+
+	// remove header from session
+	code, _, errOut = runArgs("--session", file, s.URL+"/me", "X-Token:")
+	assert.Equal(t, 0, code, errOut)
+	assert.Empty(t, last.Header.Get("X-Token"))
+
+	sess, err := loadSession(file)
+	assert.NoError(t, err)
+	assert.Empty(t, sess.Headers["X-Token"])
+	assert.Equal(t, []sessionCookie{{Name: "sid", Value: "s1", Path: "/"}}, sess.Cookies)
+}
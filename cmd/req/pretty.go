@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorKey    = "\x1b[34;1m"
+	colorString = "\x1b[32m"
+	colorNumber = "\x1b[36m"
+	colorLit    = "\x1b[35m"
+	colorHeader = "\x1b[36m"
+	colorStatus = "\x1b[33;1m"
+	colorError  = "\x1b[31;1m"
+)
+
+type printer struct {
+	out    io.Writer
+	format bool
+	color  bool
+}
+
+// isTerminal check if w is a character device
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *printer) paint(color, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (p *printer) requestHead(r *http.Request) {
+	uri := r.URL.RequestURI()
+	_, _ = fmt.Fprintf(p.out, "%s %s %s\n", p.paint(colorStatus, r.Method), uri, r.Proto)
+	h := r.Header.Clone()
+	if h.Get("Host") == "" {
+		h.Set("Host", r.URL.Host)
+	}
+	p.header(h)
+}
+
+func (p *printer) responseHead(res *http.Response) {
+	color := colorStatus
+	if res.StatusCode >= http.StatusBadRequest {
+		color = colorError
+	}
+	_, _ = fmt.Fprintf(p.out, "%s %s\n", res.Proto, p.paint(color, res.Status))
+	p.header(res.Header)
+}
+
+func (p *printer) header(h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			_, _ = fmt.Fprintf(p.out, "%s: %s\n", p.paint(colorHeader, k), v)
+		}
+	}
+	_, _ = fmt.Fprintln(p.out)
+}
+
+func (p *printer) body(contentType string, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	if p.format && (strings.Contains(contentType, "json") || json.Valid(b)) {
+		buf := &bytes.Buffer{}
+		if err := json.Indent(buf, b, "", "  "); err == nil {
+			b = buf.Bytes()
+			if p.color {
+				b = colorJSON(b)
+			}
+		}
+	}
+	_, _ = p.out.Write(b)
+	if b[len(b)-1] != '\n' {
+		_, _ = fmt.Fprintln(p.out)
+	}
+}
+
+// colorJSON add ANSI colors to valid JSON
+func colorJSON(b []byte) []byte {
+	out := &bytes.Buffer{}
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for ; j < len(b) && b[j] != '"'; j++ {
+				if b[j] == '\\' {
+					j++
+				}
+			}
+			j++
+			k := j
+			for k < len(b) && (b[k] == ' ' || b[k] == '\n' || b[k] == '\t' || b[k] == '\r') {
+				k++
+			}
+			color := colorString
+			if k < len(b) && b[k] == ':' {
+				color = colorKey
+			}
+			out.WriteString(color)
+			out.Write(b[i:j])
+			out.WriteString(colorReset)
+			i = j
+		case c == '-' || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(b) && strings.IndexByte("0123456789.eE+-", b[j]) >= 0 {
+				j++
+			}
+			out.WriteString(colorNumber)
+			out.Write(b[i:j])
+			out.WriteString(colorReset)
+			i = j
+		case c >= 'a' && c <= 'z':
+			j := i + 1
+			for j < len(b) && b[j] >= 'a' && b[j] <= 'z' {
+				j++
+			}
+			out.WriteString(colorLit)
+			out.Write(b[i:j])
+			out.WriteString(colorReset)
+			i = j
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/wenerme/go-req"
+)
+
+// session persisted headers and cookies between invocations
+type session struct {
+	path    string
+	Headers map[string]string `json:"headers,omitempty"`
+	Cookies []sessionCookie   `json:"cookies,omitempty"`
+}
+
+type sessionCookie struct {
+	Name    string     `json:"name"`
+	Value   string     `json:"value"`
+	Path    string     `json:"path,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// sessionPath resolve session name to file, name with path separator or .json suffix is used as is,
+// others are stored in user config dir per host
+func sessionPath(name string, host string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') || strings.HasSuffix(name, ".json") {
+		return name, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "req", "sessions", strings.ReplaceAll(host, ":", "_"), name+".json"), nil
+}
+
+func loadSession(path string) (*session, error) {
+	s := &session{path: path}
+	b, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+	if err = json.Unmarshal(b, s); err != nil {
+		return nil, errors.Wrapf(err, "parse session %s", path)
+	}
+	return s, nil
+}
+
+func (s *session) save() error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(b, '\n'), 0o600)
+}
+
+// hook add session headers and cookies to request, update session from request and response
+func (s *session) hook() req.Hook {
+	return req.Hook{
+		Name: "Session",
+		OnRequest: func(r *http.Request) error {
+			for k, v := range s.Headers {
+				if r.Header.Get(k) == "" {
+					r.Header.Set(k, v)
+				}
+			}
+			now := time.Now()
+			for _, c := range s.Cookies {
+				if c.Expires != nil && c.Expires.Before(now) {
+					continue
+				}
+				if c.Path != "" && !strings.HasPrefix(r.URL.Path, c.Path) {
+					continue
+				}
+				if _, err := r.Cookie(c.Name); err == nil {
+					continue
+				}
+				r.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+			}
+			return nil
+		},
+		OnResponse: func(res *http.Response) error {
+			s.update(res)
+			return s.save()
+		},
+	}
+}
+
+func (s *session) update(res *http.Response) {
+	headers := map[string]string{}
+	for k := range res.Request.Header {
+		switch {
+		case strings.HasPrefix(k, "Content-"), strings.HasPrefix(k, "If-"), k == "Cookie":
+			continue
+		}
+		headers[k] = res.Request.Header.Get(k)
+	}
+	s.Headers = headers
+
+	cookies := map[string]sessionCookie{}
+	for _, c := range s.Cookies {
+		cookies[c.Name] = c
+	}
+	for _, c := range res.Request.Cookies() {
+		if _, ok := cookies[c.Name]; !ok {
+			cookies[c.Name] = sessionCookie{Name: c.Name, Value: c.Value}
+		}
+	}
+	now := time.Now()
+	for _, c := range res.Cookies() {
+		sc := sessionCookie{Name: c.Name, Value: c.Value, Path: c.Path}
+		switch {
+		case c.MaxAge < 0:
+			delete(cookies, c.Name)
+			continue
+		case c.MaxAge > 0:
+			t := now.Add(time.Duration(c.MaxAge) * time.Second)
+			sc.Expires = &t
+		case !c.Expires.IsZero():
+			if c.Expires.Before(now) {
+				delete(cookies, c.Name)
+				continue
+			}
+			t := c.Expires
+			sc.Expires = &t
+		}
+		cookies[c.Name] = sc
+	}
+	s.Cookies = s.Cookies[:0]
+	for _, c := range cookies {
+		s.Cookies = append(s.Cookies, c)
+	}
+	sort.Slice(s.Cookies, func(i, j int) bool {
+		return s.Cookies[i].Name < s.Cookies[j].Name
+	})
+}
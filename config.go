@@ -0,0 +1,605 @@
+package req
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ClientConfig declarative definition of client, build Request by ClientConfig.Request
+type ClientConfig struct {
+	BaseURL  string            `yaml:"baseURL"`
+	Headers  map[string]string `yaml:"headers"`
+	Query    map[string]string `yaml:"query"`
+	Encoding string            `yaml:"encoding"` // Encoding of body, json, form or multipart, default json
+	Timeout  time.Duration     `yaml:"timeout"`  // Timeout of each attempt, e.g. 10s
+	Auth     *AuthConfig       `yaml:"auth"`
+	Retry    *RetryOptions     `yaml:"retry"`
+	Hooks    []HookConfig      `yaml:"hooks"` // Hooks built by registered HookFactory
+}
+
+// AuthConfig auth of ClientConfig
+type AuthConfig struct {
+	Type           string `yaml:"type"` // Type basic, bearer, header or oauth1
+	Username       string `yaml:"username"`
+	Password       string `yaml:"password"`
+	Token          string `yaml:"token"`  // Token for bearer and oauth1
+	Header         string `yaml:"header"` // Header name for header type, default X-API-Key
+	Value          string `yaml:"value"`  // Value of header for header type
+	ConsumerKey    string `yaml:"consumerKey"`
+	ConsumerSecret string `yaml:"consumerSecret"`
+	TokenSecret    string `yaml:"tokenSecret"`
+}
+
+// HookConfig named hook with options, plain string is the name of hook without options
+type HookConfig struct {
+	Name    string                 `yaml:"name"`
+	Options map[string]interface{} `yaml:"options"`
+}
+
+// UnmarshalYAML support plain hook name
+func (h *HookConfig) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		h.Name = n.Value
+		return nil
+	}
+	type plain HookConfig
+	return n.Decode((*plain)(h))
+}
+
+// HookFactory build Hook from options, decode options into struct, duration accept string like 1s,
+// func(*http.Request) string field accept "host" as HostKey
+type HookFactory func(decode func(out interface{}) error) (Hook, error)
+
+var hookFactories = struct {
+	sync.RWMutex
+	m map[string]HookFactory
+}{m: map[string]HookFactory{}}
+
+// RegisterHookFactory register HookFactory by name for HookConfig, override existing one
+func RegisterHookFactory(name string, f HookFactory) {
+	hookFactories.Lock()
+	defer hookFactories.Unlock()
+	hookFactories.m[name] = f
+}
+
+func lookupHookFactory(name string) (HookFactory, bool) {
+	hookFactories.RLock()
+	defer hookFactories.RUnlock()
+	f, ok := hookFactories.m[name]
+	return f, ok
+}
+
+func init() {
+	for name, f := range map[string]HookFactory{
+		"debug": func(decode func(out interface{}) error) (Hook, error) {
+			c := &debugConfig{}
+			if err := decode(c); err != nil {
+				return Hook{}, err
+			}
+			o, err := c.options()
+			if err != nil {
+				return Hook{}, err
+			}
+			return DebugHook(o), nil
+		},
+		"transport": func(decode func(out interface{}) error) (Hook, error) {
+			o := &TransportOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return TransportHook(o), nil
+		},
+		"tls": func(decode func(out interface{}) error) (Hook, error) {
+			c := &tlsConfig{}
+			if err := decode(c); err != nil {
+				return Hook{}, err
+			}
+			o, err := c.options()
+			if err != nil {
+				return Hook{}, err
+			}
+			return TLSHook(o), nil
+		},
+		"proxy": func(decode func(out interface{}) error) (Hook, error) {
+			o := &ProxyOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return ProxyHook(o), nil
+		},
+		"resolve": func(decode func(out interface{}) error) (Hook, error) {
+			o := &ResolveOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return ResolveHook(o), nil
+		},
+		"rateLimit": func(decode func(out interface{}) error) (Hook, error) {
+			o := &RateLimitOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return RateLimitHook(o), nil
+		},
+		"circuitBreaker": func(decode func(out interface{}) error) (Hook, error) {
+			o := &CircuitBreakerOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return CircuitBreakerHook(o), nil
+		},
+		"bulkhead": func(decode func(out interface{}) error) (Hook, error) {
+			o := &BulkheadOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return BulkheadHook(o), nil
+		},
+		"hedge": func(decode func(out interface{}) error) (Hook, error) {
+			o := &HedgeOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return HedgeHook(o), nil
+		},
+		"singleflight": func(decode func(out interface{}) error) (Hook, error) {
+			o := &SingleflightOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return SingleflightHook(o), nil
+		},
+		"retry": func(decode func(out interface{}) error) (Hook, error) {
+			o := &RetryOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return RetryHook(o), nil
+		},
+		"timeout": func(decode func(out interface{}) error) (Hook, error) {
+			o := &struct{ Timeout time.Duration }{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return TimeoutHook(o.Timeout), nil
+		},
+		"fault": func(decode func(out interface{}) error) (Hook, error) {
+			o := &FaultOptions{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return FaultHook(o), nil
+		},
+		"contentDigest": func(decode func(out interface{}) error) (Hook, error) {
+			o := &struct{ Algorithms []string }{}
+			if err := decode(o); err != nil {
+				return Hook{}, err
+			}
+			return ContentDigestHook(o.Algorithms...), nil
+		},
+	} {
+		RegisterHookFactory(name, f)
+	}
+}
+
+// LoadClientConfig load named clients from YAML or JSON file under clients key, see ParseClientConfig
+func LoadClientConfig(path string) (map[string]*ClientConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseClientConfig(b, nil)
+}
+
+// ParseClientConfig parse named clients from YAML or JSON under clients key.
+// ${VAR}, ${VAR:-default} and ${VAR:?message} in values are expanded by lookup, default os.LookupEnv, $$ for literal $.
+func ParseClientConfig(data []byte, lookup func(key string) (string, bool)) (map[string]*ClientConfig, error) {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "parse client config")
+	}
+	if err := expandNode(&doc, lookup); err != nil {
+		return nil, err
+	}
+	var file struct {
+		Clients map[string]*ClientConfig `yaml:"clients"`
+	}
+	if err := doc.Decode(&file); err != nil {
+		return nil, errors.Wrap(err, "decode client config")
+	}
+	return file.Clients, nil
+}
+
+// Request build Request of config
+func (c *ClientConfig) Request() (Request, error) {
+	r := Request{BaseURL: c.BaseURL}
+	if len(c.Headers) > 0 {
+		r.Header = http.Header{}
+		for k, v := range c.Headers {
+			r.Header.Set(k, v)
+		}
+	}
+	if len(c.Query) > 0 {
+		q := url.Values{}
+		for k, v := range c.Query {
+			q.Set(k, v)
+		}
+		r.Query = q
+	}
+
+	switch strings.ToLower(c.Encoding) {
+	case "", "json":
+		r = r.WithHook(JSONEncode, JSONDecode)
+	case "form":
+		r = r.WithHook(FormEncode, JSONDecode)
+	case "multipart":
+		r = r.WithHook(MultipartFormEncode, JSONDecode)
+	default:
+		return r, errors.Errorf("unsupported encoding %q", c.Encoding)
+	}
+	if c.Timeout > 0 {
+		r = r.WithHook(TimeoutHook(c.Timeout))
+	}
+	if c.Retry != nil {
+		r = r.WithHook(RetryHook(c.Retry))
+	}
+	if c.Auth != nil {
+		h, err := c.Auth.hook()
+		if err != nil {
+			return r, err
+		}
+		r = r.WithHook(h)
+	}
+	for _, hc := range c.Hooks {
+		f, ok := lookupHookFactory(hc.Name)
+		if !ok {
+			return r, errors.Errorf("unknown hook %q", hc.Name)
+		}
+		h, err := f(func(out interface{}) error {
+			return decodeHookOptions(hc.Options, out)
+		})
+		if err != nil {
+			return r, errors.Wrapf(err, "build hook %s", hc.Name)
+		}
+		r = r.WithHook(h)
+	}
+	return r, nil
+}
+
+func (a *AuthConfig) hook() (Hook, error) {
+	switch strings.ToLower(a.Type) {
+	case "basic":
+		username, password := a.Username, a.Password
+		return Hook{
+			Name: "BasicAuth",
+			OnRequest: func(r *http.Request) error {
+				r.SetBasicAuth(username, password)
+				return nil
+			},
+		}, nil
+	case "bearer":
+		token := a.Token
+		return Hook{
+			Name: "BearerAuth",
+			OnRequest: func(r *http.Request) error {
+				r.Header.Set("Authorization", "Bearer "+token)
+				return nil
+			},
+		}, nil
+	case "header":
+		name, value := a.Header, a.Value
+		if name == "" {
+			name = "X-API-Key"
+		}
+		return Hook{
+			Name: "HeaderAuth",
+			OnRequest: func(r *http.Request) error {
+				r.Header.Set(name, value)
+				return nil
+			},
+		}, nil
+	case "oauth1":
+		return OAuth1Hook(&OAuth1Options{
+			ConsumerKey:    a.ConsumerKey,
+			ConsumerSecret: a.ConsumerSecret,
+			Token:          a.Token,
+			TokenSecret:    a.TokenSecret,
+		}), nil
+	}
+	return Hook{}, errors.Errorf("unsupported auth type %q", a.Type)
+}
+
+// debugConfig options of debug hook in config
+type debugConfig struct {
+	Disable   bool
+	Body      bool
+	ErrorOnly bool
+	Curl      bool
+	Out       string // Out stderr, stdout or file path to append, default stderr, file is opened once per path and kept open
+}
+
+func (c debugConfig) options() (*DebugOptions, error) {
+	o := &DebugOptions{Disable: c.Disable, Body: c.Body, ErrorOnly: c.ErrorOnly, Curl: c.Curl}
+	switch c.Out {
+	case "", "stderr":
+	case "stdout":
+		o.Out = os.Stdout
+	default:
+		f, err := openDebugOut(c.Out)
+		if err != nil {
+			return nil, err
+		}
+		o.Out = f
+	}
+	return o, nil
+}
+
+// debugOuts files opened by debug hook config, shared by path
+var debugOuts = struct {
+	sync.Mutex
+	m map[string]*os.File
+}{m: map[string]*os.File{}}
+
+func openDebugOut(path string) (*os.File, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	debugOuts.Lock()
+	defer debugOuts.Unlock()
+	if f, ok := debugOuts.m[abs]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(abs, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, errors.Wrap(err, "open debug out")
+	}
+	debugOuts.m[abs] = f
+	return f, nil
+}
+
+// tlsConfig options of tls hook in config, PEM are plain strings, MinVersion like 1.2
+type tlsConfig struct {
+	CertFile           string
+	KeyFile            string
+	CertPEM            string
+	KeyPEM             string
+	RootCAFiles        []string
+	RootCAPEM          string
+	Pins               []string
+	MinVersion         string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+func (c tlsConfig) options() (*TLSOptions, error) {
+	o := &TLSOptions{
+		CertFile:           c.CertFile,
+		KeyFile:            c.KeyFile,
+		RootCAFiles:        c.RootCAFiles,
+		Pins:               c.Pins,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CertPEM != "" || c.KeyPEM != "" {
+		o.CertPEM, o.KeyPEM = []byte(c.CertPEM), []byte(c.KeyPEM)
+	}
+	if c.RootCAPEM != "" {
+		o.RootCAPEM = []byte(c.RootCAPEM)
+	}
+	switch strings.TrimPrefix(strings.ToLower(c.MinVersion), "tls") {
+	case "":
+	case "1.0":
+		o.MinVersion = tls.VersionTLS10
+	case "1.1":
+		o.MinVersion = tls.VersionTLS11
+	case "1.2":
+		o.MinVersion = tls.VersionTLS12
+	case "1.3":
+		o.MinVersion = tls.VersionTLS13
+	default:
+		return nil, errors.Errorf("invalid tls minVersion %q", c.MinVersion)
+	}
+	return o, nil
+}
+
+var singleVarPattern = regexp.MustCompile(`^\$\{[^}]*\}$`)
+
+// expandNode expand variables of scalar values, mapping keys are kept, plain scalar of a single variable is
+// re-resolved so ${PORT} can be a number, other expanded scalars are strings
+func expandNode(n *yaml.Node, lookup func(string) (string, bool)) error {
+	if n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "$") {
+		v, err := expandVars(n.Value, lookup)
+		if err != nil {
+			return errors.Wrapf(err, "line %d", n.Line)
+		}
+		if v != n.Value && n.Style == 0 {
+			n.Tag = "!!str"
+			if singleVarPattern.MatchString(n.Value) {
+				// value of single variable can be number or bool, null is kept as string
+				if (&yaml.Node{Kind: yaml.ScalarNode, Value: v}).ShortTag() != "!!null" {
+					n.Tag = ""
+				}
+			}
+		}
+		n.Value = v
+	}
+	for i, c := range n.Content {
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		if err := expandNode(c, lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandVars expand ${VAR}, ${VAR:-default}, ${VAR:?message} and $$
+func expandVars(s string, lookup func(string) (string, bool)) (string, error) {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i == len(s)-1 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		sb.WriteString(s[:i])
+		switch s[i+1] {
+		case '$':
+			sb.WriteByte('$')
+			s = s[i+2:]
+			continue
+		case '{':
+		default:
+			sb.WriteByte('$')
+			s = s[i+1:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", errors.Errorf("unclosed variable in %q", s)
+		}
+		expr := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		name, op, arg := expr, "", ""
+		if j := strings.Index(expr, ":"); j >= 0 && j+1 < len(expr) && (expr[j+1] == '-' || expr[j+1] == '?') {
+			name, op, arg = expr[:j], expr[j:j+2], expr[j+2:]
+		}
+		v, _ := lookup(name)
+		switch {
+		case op == ":-" && v == "":
+			v = arg
+		case op == ":?" && v == "":
+			if arg == "" {
+				arg = "not set"
+			}
+			return "", errors.Errorf("variable %s: %s", name, arg)
+		}
+		sb.WriteString(v)
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var hostKeyType = reflect.TypeOf(HostKey)
+
+// decodeHookOptions decode options into out by json rule, with duration string and host key support
+func decodeHookOptions(options map[string]interface{}, out interface{}) error {
+	if len(options) == 0 {
+		return nil
+	}
+	rv := reflect.ValueOf(out).Elem()
+	var hostKeys []int
+	m := make(map[string]interface{}, len(options))
+	for k, v := range options {
+		m[k] = v
+	}
+	if rv.Kind() == reflect.Struct {
+		for k, v := range m {
+			if i, ok := structFieldIndex(rv.Type(), k); ok && rv.Type().Field(i).Type == hostKeyType && v == "host" {
+				hostKeys = append(hostKeys, i)
+				delete(m, k)
+			}
+		}
+	}
+	v, err := normalizeOptions(m, rv.Type())
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(out); err != nil {
+		return err
+	}
+	for _, i := range hostKeys {
+		rv.Field(i).Set(reflect.ValueOf(HostKey))
+	}
+	return nil
+}
+
+// normalizeOptions convert yaml value for json decoding of type t, duration string is parsed
+func normalizeOptions(v interface{}, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch vv := v.(type) {
+	case string:
+		if t == durationType {
+			d, err := time.ParseDuration(vv)
+			if err != nil {
+				return nil, err
+			}
+			return int64(d), nil
+		}
+	case []interface{}:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return v, nil
+		}
+		out := make([]interface{}, len(vv))
+		for i, e := range vv {
+			n, err := normalizeOptions(e, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			out[i] = n
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(vv))
+		for k, e := range vv {
+			et := reflect.TypeOf((*interface{})(nil)).Elem()
+			switch t.Kind() {
+			case reflect.Map:
+				et = t.Elem()
+			case reflect.Struct:
+				if i, ok := structFieldIndex(t, k); ok {
+					et = t.Field(i).Type
+				}
+			}
+			n, err := normalizeOptions(e, et)
+			if err != nil {
+				return nil, errors.Wrap(err, k)
+			}
+			out[k] = n
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// structFieldIndex find field by json name or field name, case-insensitive
+func structFieldIndex(t reflect.Type, name string) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		n := f.Name
+		if tag, _, _ := cutString(f.Tag.Get("json"), ","); tag != "" {
+			n = tag
+		}
+		if strings.EqualFold(n, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
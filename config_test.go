@@ -0,0 +1,265 @@
+package req_test
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestClientConfig(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		token := r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"token":"` + token + `","version":"` + r.Header.Get("X-Version") + `","q":"` + r.URL.RawQuery + `"}`))
+	}))
+	defer server.Close()
+
+	env := map[string]string{"API_URL": server.URL, "API_TOKEN": "secret", "API_VERSION": "2"}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+	clients, err := req.ParseClientConfig([]byte(`
+clients:
+  api:
+    baseURL: ${API_URL}
+    headers:
+      X-Version: ${API_VERSION}
+    query:
+      lang: ${API_LANG:-en}
+    timeout: 1s
+    auth:
+      type: bearer
+      token: ${API_TOKEN}
+    retry:
+      maxRetries: 2
+      backoff: 1ms
+    hooks:
+      - debug
+      - name: rateLimit
+        options:
+          rate: ${API_RATE:-100}
+          burst: 10
+          key: host
+      - name: circuitBreaker
+        options:
+          failureThreshold: 10
+          openTimeout: 5s
+  price:
+    baseURL: "https://$${HOST}"
+`), lookup)
+	assert.NoError(t, err)
+	assert.Len(t, clients, 2)
+	api := clients["api"]
+	assert.Equal(t, server.URL, api.BaseURL)
+	assert.Equal(t, map[string]string{"X-Version": "2"}, api.Headers)
+	assert.Equal(t, map[string]string{"lang": "en"}, api.Query)
+	assert.Equal(t, time.Second, api.Timeout)
+	assert.Equal(t, &req.RetryOptions{MaxRetries: 2, Backoff: time.Millisecond}, api.Retry)
+	assert.Equal(t, "debug", api.Hooks[0].Name)
+	assert.Equal(t, 100, api.Hooks[1].Options["rate"])
+	assert.Equal(t, "https://${HOST}", clients["price"].BaseURL)
+
+	api.Hooks = api.Hooks[1:]
+	client, err := api.Request()
+	assert.NoError(t, err)
+	var out struct {
+		Token   string
+		Version string
+		Q       string
+	}
+	assert.NoError(t, client.With(req.Request{URL: "/me"}).Fetch(&out))
+	assert.Equal(t, "Bearer secret", out.Token)
+	assert.Equal(t, "2", out.Version)
+	assert.Equal(t, "lang=en", out.Q)
+	assert.Equal(t, int32(2), calls)
+
+	// expanded null is a string
+	env["NULL"], env["TILDE"] = "null", "~"
+	clients, err = req.ParseClientConfig([]byte(`
+clients:
+  a:
+    baseURL: http://${NULL}
+    auth:
+      type: basic
+      username: ${NULL}
+      password: ${TILDE}
+`), lookup)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://null", clients["a"].BaseURL)
+	assert.Equal(t, &req.AuthConfig{Type: "basic", Username: "null", Password: "~"}, clients["a"].Auth)
+
+	_, err = req.ParseClientConfig([]byte("clients: {a: {baseURL: '${MISSING:?required}'}}"), lookup)
+	assert.EqualError(t, err, "line 1: variable MISSING: required")
+
+	for _, c := range []req.ClientConfig{
+		{Encoding: "xml"},
+		{Auth: &req.AuthConfig{Type: "digest"}},
+		{Hooks: []req.HookConfig{{Name: "missing"}}},
+		{Hooks: []req.HookConfig{{Name: "rateLimit", Options: map[string]interface{}{"unknown": 1}}}},
+		{Hooks: []req.HookConfig{{Name: "bulkhead", Options: map[string]interface{}{"queueTimeout": "soon"}}}},
+	} {
+		_, err = c.Request()
+		assert.Error(t, err, "%+v", c)
+	}
+}
+
+func TestClientConfigHooks(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName + " " + r.Header.Get("X-Name")))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	certPEM, keyPEM := generateCert(t, "client")
+	out := filepath.Join(t.TempDir(), "debug.log")
+	env := map[string]string{
+		"URL":  server.URL,
+		"CA":   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+		"CERT": string(certPEM),
+		"KEY":  string(keyPEM),
+		"OUT":  out,
+		"NAME": "X-Expanded",
+	}
+	clients, err := req.ParseClientConfig([]byte(`
+clients:
+  api:
+    baseURL: ${URL}
+    headers:
+      X-Name: ${NAME}
+    query:
+      ${NAME}: key
+    hooks:
+      - name: tls
+        options:
+          rootCAPEM: "${CA}"
+          certPEM: "${CERT}"
+          keyPEM: "${KEY}"
+          minVersion: "1.3"
+      - name: debug
+        options:
+          out: ${OUT}
+`), func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	})
+	assert.NoError(t, err)
+	// keys are not expanded
+	assert.Equal(t, map[string]string{"X-Name": "X-Expanded"}, clients["api"].Headers)
+	assert.Equal(t, map[string]string{"${NAME}": "key"}, clients["api"].Query)
+
+	client, err := clients["api"].Request()
+	assert.NoError(t, err)
+	s, _, err := client.FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "client X-Expanded", s)
+	b, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "X-Name: X-Expanded")
+
+	for _, options := range []map[string]interface{}{
+		{"minVersion": "1.4"},
+		{"minVersion": 771},
+		{"out": filepath.Join(t.TempDir(), "missing", "debug.log")},
+	} {
+		name := "tls"
+		if _, ok := options["out"]; ok {
+			name = "debug"
+		}
+		c := req.ClientConfig{Hooks: []req.HookConfig{{Name: name, Options: options}}}
+		_, err = c.Request()
+		assert.Error(t, err, "%v", options)
+	}
+}
+
+func TestClientConfigDebugOut(t *testing.T) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("no /proc/self/fd")
+	}
+	out := filepath.Join(t.TempDir(), "debug.log")
+	c := req.ClientConfig{Hooks: []req.HookConfig{{Name: "debug", Options: map[string]interface{}{"out": out}}}}
+	for i := 0; i < 10; i++ {
+		_, err = c.Request()
+		assert.NoError(t, err)
+	}
+	after, err := os.ReadDir("/proc/self/fd")
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(after), len(fds)+1)
+}
+
+func TestClientConfigAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		_, _ = w.Write([]byte(r.Header.Get("Authorization") + r.Header.Get("X-API-Key")))
+	}))
+	defer server.Close()
+
+	for _, test := range []struct {
+		auth   req.AuthConfig
+		expect string
+	}{
+		{req.AuthConfig{Type: "basic", Username: "user", Password: "pass"}, "Basic dXNlcjpwYXNz"},
+		{req.AuthConfig{Type: "bearer", Token: "t"}, "Bearer t"},
+		{req.AuthConfig{Type: "header", Value: "k"}, "k"},
+	} {
+		c := req.ClientConfig{BaseURL: server.URL, Auth: &test.auth}
+		client, err := c.Request()
+		assert.NoError(t, err)
+		s, _, err := client.FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, test.expect, s)
+	}
+
+	c := req.ClientConfig{BaseURL: server.URL, Auth: &req.AuthConfig{Type: "oauth1", ConsumerKey: "ck", ConsumerSecret: "cs"}}
+	client, err := c.Request()
+	assert.NoError(t, err)
+	s, _, err := client.FetchString()
+	assert.NoError(t, err)
+	assert.Contains(t, s, `OAuth oauth_consumer_key="ck"`)
+}
+
+func TestLoadClientConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Client")))
+	}))
+	defer server.Close()
+
+	req.RegisterHookFactory("clientName", func(decode func(out interface{}) error) (req.Hook, error) {
+		var o struct{ Name string }
+		if err := decode(&o); err != nil {
+			return req.Hook{}, err
+		}
+		return req.Hook{
+			Name: "ClientName",
+			OnRequest: func(r *http.Request) error {
+				r.Header.Set("X-Client", o.Name)
+				return nil
+			},
+		}, nil
+	})
+
+	file := filepath.Join(t.TempDir(), "clients.json")
+	assert.NoError(t, os.WriteFile(file, []byte(`{"clients": {"a": {"baseURL": "`+server.URL+`", "hooks": [{"name": "clientName", "options": {"name": "go-req"}}]}}}`), 0o600))
+	clients, err := req.LoadClientConfig(file)
+	assert.NoError(t, err)
+	client, err := clients["a"].Request()
+	assert.NoError(t, err)
+	s, _, err := client.FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "go-req", s)
+}
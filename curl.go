@@ -0,0 +1,139 @@
+package req
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// CurlOptions options for CurlCommand
+type CurlOptions struct {
+	MaxInlineBody int    // MaxInlineBody larger or binary body is not inlined, default 4096
+	BodyDir       string // BodyDir write body not inlined to a file in dir and use --data-binary @file, the caller removes the file; when empty the body is omitted and read from stdin
+	Multiline     bool   // Multiline break arguments into lines
+}
+
+// Curl reconcile Request and return equivalent curl command
+func (r Request) Curl() (string, error) {
+	req, err := r.NewRequest()
+	if err != nil {
+		return "", err
+	}
+	return CurlCommand(req, nil)
+}
+
+// CurlCommand build shell escaped curl command of http.Request, TLS, proxy and resolve flags are from
+// transport hooks of Request in context, settings curl can not express are listed as comment lines
+// before the command
+func CurlCommand(r *http.Request, o *CurlOptions) (string, error) {
+	if o == nil {
+		o = &CurlOptions{}
+	}
+	maxInline := o.MaxInlineBody
+	if maxInline <= 0 {
+		maxInline = 4096 //nolint:gomnd
+	}
+	body, err := requestBody(r)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"curl"}
+	var comments []string
+	switch {
+	case r.Method == http.MethodGet && len(body) == 0:
+	case r.Method == http.MethodPost && len(body) > 0:
+	case r.Method == http.MethodHead && len(body) == 0:
+		args = append(args, "-I")
+	default:
+		args = append(args, "-X "+shellQuote(r.Method))
+	}
+	if strings.ContainsAny(r.URL.String(), "[]{}") {
+		// brackets and braces are url globbing of curl
+		args = append(args, "-g")
+	}
+
+	if re := FromContext(r.Context()); re != nil {
+		for _, v := range re.Extension.Hooks {
+			if v.curl != nil {
+				a, c := v.curl(r)
+				args = append(args, a...)
+				comments = append(comments, c...)
+			}
+		}
+	}
+
+	if r.Host != "" && r.Host != r.URL.Host {
+		args = append(args, "-H "+shellQuote("Host: "+r.Host))
+	}
+	keys := make([]string, 0, len(r.Header))
+	for k := range r.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range r.Header[k] {
+			switch {
+			case v != "":
+				args = append(args, "-H "+shellQuote(k+": "+v))
+			case k == "User-Agent":
+				// empty User-Agent suppress the default
+				args = append(args, "-H "+shellQuote(k+":"))
+			default:
+				args = append(args, "-H "+shellQuote(k+";"))
+			}
+		}
+	}
+
+	switch {
+	case len(body) == 0:
+	case len(body) <= maxInline && utf8.Valid(body) && bytes.IndexByte(body, 0) < 0:
+		// --data-raw never read @file
+		args = append(args, "--data-raw "+shellQuote(string(body)))
+	case o.BodyDir == "":
+		comments = append(comments, fmt.Sprintf("# body of %d bytes omitted, pipe it to stdin", len(body)))
+		args = append(args, "--data-binary @-")
+	default:
+		f, err := os.CreateTemp(o.BodyDir, "req-body-*.bin")
+		if err != nil {
+			return "", err
+		}
+		_, err = f.Write(body)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "--data-binary "+shellQuote("@"+f.Name()))
+	}
+	args = append(args, shellQuote(r.URL.String()))
+
+	sep := " "
+	if o.Multiline {
+		sep = " \\\n  "
+	}
+	return strings.Join(append(comments, strings.Join(args, sep)), "\n"), nil
+}
+
+// shellQuote quote s for POSIX shell
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_@%+=:,./-", c)) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,419 @@
+package req
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// curl options without argument, ignored when not supported
+var curlFlags = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true, "-L": true, "--location": true,
+	"-v": true, "--verbose": true, "-i": true, "--include": true, "-f": true, "--fail": true,
+	"-N": true, "--no-buffer": true, "-g": true, "--globoff": true, "--compressed": true,
+	"-k": true, "--insecure": true, "-G": true, "--get": true, "-I": true, "--head": true,
+	"--http1.1": true, "--http2": true, "--tlsv1.2": true, "--tlsv1.3": true,
+}
+
+// curl options with argument
+var curlArgFlags = map[string]bool{
+	"-X": true, "--request": true, "-H": true, "--header": true, "-d": true, "--data": true,
+	"--data-raw": true, "--data-ascii": true, "--data-binary": true, "--data-urlencode": true, "--json": true,
+	"-F": true, "--form": true, "-u": true, "--user": true, "-A": true, "--user-agent": true,
+	"-e": true, "--referer": true, "-b": true, "--cookie": true, "--url": true,
+	"-x": true, "--proxy": true, "--resolve": true, "--unix-socket": true,
+	"--cacert": true, "-E": true, "--cert": true, "--key": true,
+	"-o": true, "--output": true, "-m": true, "--max-time": true, "--connect-timeout": true,
+	"-w": true, "--write-out": true, "--retry": true,
+}
+
+// ParseCurl parse curl command line into Request, the reverse of Request.Curl
+//
+// Supports -X, -H, -d, --data-*, --json, -F, -u, -G, -I, -A, -e, -b, -k, -x, --resolve,
+// --unix-socket, --cacert, --cert and --key, --compressed is implied by transport.
+func ParseCurl(cmd string) (Request, error) {
+	args, err := splitShell(cmd)
+	if err != nil {
+		return Request{}, err
+	}
+	if len(args) == 0 || args[0] != "curl" {
+		return Request{}, errors.New("curl: not a curl command")
+	}
+	args = args[1:]
+
+	r := Request{Header: http.Header{}}
+	var (
+		rawURL  string
+		data    []string
+		form    []string
+		get     bool
+		head    bool
+		tlsOpts TLSOptions
+		hasTLS  bool
+		hosts   = map[string][]string{}
+		isJSON  bool // last data is from --json
+	)
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if !strings.HasPrefix(flag, "-") || flag == "-" {
+			rawURL = flag
+			continue
+		}
+		// short option with attached value or combined flags, e.g. -XPOST, -sSL, -sXPOST
+		if !strings.HasPrefix(flag, "--") && len(flag) > 2 {
+			var expanded []string
+			for j := 1; j < len(flag); j++ {
+				short := "-" + flag[j:j+1]
+				expanded = append(expanded, short)
+				if curlArgFlags[short] {
+					if j+1 < len(flag) {
+						expanded = append(expanded, flag[j+1:])
+					}
+					break
+				}
+			}
+			args = append(args[:i], append(expanded, args[i+1:]...)...)
+			flag = args[i]
+		}
+		var val string
+		switch {
+		case curlArgFlags[flag]:
+			if i+1 >= len(args) {
+				return r, errors.Errorf("curl: option %v requires value", flag)
+			}
+			i++
+			val = args[i]
+		case curlFlags[flag]:
+		default:
+			return r, errors.Errorf("curl: unsupported option %v", flag)
+		}
+
+		switch flag {
+		case "-X", "--request":
+			r.Method = val
+		case "-H", "--header":
+			k, v, ok := cutString(val, ":")
+			switch {
+			case !ok:
+				// "Name;" send empty header
+				r.Header.Add(strings.TrimSpace(strings.TrimSuffix(val, ";")), "")
+			case strings.TrimSpace(v) == "":
+				// "Name:" remove header, empty User-Agent suppress the default
+				k = strings.TrimSpace(k)
+				r.Header.Del(k)
+				if http.CanonicalHeaderKey(k) == "User-Agent" {
+					r.Header.Set(k, "")
+				}
+			default:
+				r.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+			}
+		case "-d", "--data", "--data-ascii", "--data-raw", "--data-binary":
+			if strings.HasPrefix(val, "@") && flag != "--data-raw" {
+				b, err := os.ReadFile(val[1:])
+				if err != nil {
+					return r, errors.Wrap(err, "curl: read data")
+				}
+				if flag != "--data-binary" {
+					b = bytes.ReplaceAll(bytes.ReplaceAll(b, []byte("\r"), nil), []byte("\n"), nil)
+				}
+				val = string(b)
+			}
+			data = append(data, val)
+			isJSON = false
+		case "--data-urlencode":
+			v, err := curlURLEncode(val)
+			if err != nil {
+				return r, err
+			}
+			data = append(data, v)
+			isJSON = false
+		case "--json":
+			// json pieces are concatenated as is
+			if n := len(data); n > 0 && isJSON {
+				data[n-1] += val
+			} else {
+				data = append(data, val)
+			}
+			isJSON = true
+			if r.Header.Get("Content-Type") == "" {
+				r.Header.Set("Content-Type", "application/json")
+			}
+			if r.Header.Get("Accept") == "" {
+				r.Header.Set("Accept", "application/json")
+			}
+		case "-F", "--form":
+			form = append(form, val)
+		case "-u", "--user":
+			r.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(val)))
+		case "-A", "--user-agent":
+			r.Header.Set("User-Agent", val)
+		case "-e", "--referer":
+			r.Header.Set("Referer", val)
+		case "-b", "--cookie":
+			if !strings.Contains(val, "=") {
+				return r, errors.New("curl: cookie file is not supported")
+			}
+			r.Header.Add("Cookie", val)
+		case "--url":
+			rawURL = val
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			head = true
+		case "-k", "--insecure":
+			tlsOpts.InsecureSkipVerify = true
+			hasTLS = true
+		case "--tlsv1.2":
+			tlsOpts.MinVersion = tls.VersionTLS12
+			hasTLS = true
+		case "--tlsv1.3":
+			tlsOpts.MinVersion = tls.VersionTLS13
+			hasTLS = true
+		case "--cacert":
+			tlsOpts.RootCAFiles = append(tlsOpts.RootCAFiles, val)
+			hasTLS = true
+		case "-E", "--cert":
+			// cert:password is not supported
+			tlsOpts.CertFile = val
+			if tlsOpts.KeyFile == "" {
+				tlsOpts.KeyFile = val
+			}
+			hasTLS = true
+		case "--key":
+			tlsOpts.KeyFile = val
+			hasTLS = true
+		case "-x", "--proxy":
+			if !strings.Contains(val, "://") {
+				val = "http://" + val
+			}
+			r.Extension.With(ProxyHook(&ProxyOptions{URL: val}))
+		case "--resolve":
+			// host:port:addr
+			parts := strings.SplitN(val, ":", 3)
+			if len(parts) != 3 {
+				return r, errors.Errorf("curl: invalid resolve %q", val)
+			}
+			addr := strings.Trim(parts[2], "[]")
+			hosts[parts[0]+":"+parts[1]] = append(hosts[parts[0]+":"+parts[1]], addr)
+		case "--unix-socket":
+			r.Extension.With(UnixSocketHook(val))
+		}
+	}
+	if rawURL == "" {
+		return r, errors.New("curl: url required")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return r, errors.Wrap(err, "curl: invalid url")
+	}
+	r.RawQuery = u.RawQuery
+	u.RawQuery = ""
+	r.URL = u.String()
+
+	if hasTLS {
+		r.Extension.With(TLSHook(&tlsOpts))
+	}
+	if len(hosts) > 0 {
+		r.Extension.With(ResolveHook(&ResolveOptions{Hosts: hosts}))
+	}
+
+	method := http.MethodGet
+	switch {
+	case len(form) > 0:
+		if len(data) > 0 {
+			return r, errors.New("curl: -F can not mix with -d")
+		}
+		method = http.MethodPost
+		if r.RawBody, err = curlMultipart(r.Header, form); err != nil {
+			return r, err
+		}
+	case len(data) > 0 && get:
+		q := strings.Join(data, "&")
+		if r.RawQuery != "" {
+			q = r.RawQuery + "&" + q
+		}
+		r.RawQuery = q
+	case len(data) > 0:
+		method = http.MethodPost
+		r.RawBody = []byte(strings.Join(data, "&"))
+		if r.Header.Get("Content-Type") == "" {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	case head:
+		method = http.MethodHead
+	}
+	if r.Method == "" {
+		r.Method = method
+	}
+	if len(r.Header) == 0 {
+		r.Header = nil
+	}
+	return r, nil
+}
+
+// curlURLEncode encode --data-urlencode value, support content, =content, name=content, @file and name@file
+func curlURLEncode(v string) (string, error) {
+	name, content := "", v
+	if i := strings.IndexAny(v, "=@"); i >= 0 {
+		name, content = v[:i], v[i+1:]
+		if v[i] == '@' {
+			b, err := os.ReadFile(content)
+			if err != nil {
+				return "", errors.Wrap(err, "curl: read data")
+			}
+			content = string(b)
+		}
+	}
+	if name == "" {
+		return oauth1Escape(content), nil
+	}
+	return name + "=" + oauth1Escape(content), nil
+}
+
+// curlMultipart build multipart body of -F values, name=value, name=@file[;type=mime] and name=<file
+func curlMultipart(h http.Header, form []string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	for _, f := range form {
+		name, val, ok := cutString(f, "=")
+		if !ok {
+			return nil, errors.Errorf("curl: invalid form %q", f)
+		}
+		switch {
+		case strings.HasPrefix(val, "@"):
+			file, typ, _ := cutString(val[1:], ";")
+			typ = strings.TrimPrefix(typ, "type=")
+			if typ == "" {
+				typ = "application/octet-stream"
+			}
+			b, err := os.ReadFile(file)
+			if err != nil {
+				return nil, errors.Wrap(err, "curl: read form file")
+			}
+			ph := textproto.MIMEHeader{}
+			ph.Set("Content-Disposition", multipartDisposition(name, filepath.Base(file)))
+			ph.Set("Content-Type", typ)
+			pw, err := w.CreatePart(ph)
+			if err != nil {
+				return nil, err
+			}
+			_, _ = pw.Write(b)
+		case strings.HasPrefix(val, "<"):
+			b, err := os.ReadFile(val[1:])
+			if err != nil {
+				return nil, errors.Wrap(err, "curl: read form file")
+			}
+			_ = w.WriteField(name, string(b))
+		default:
+			_ = w.WriteField(name, val)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	h.Set("Content-Type", w.FormDataContentType())
+	return buf.Bytes(), nil
+}
+
+func multipartDisposition(name, filename string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `form-data; name="` + r.Replace(name) + `"; filename="` + r.Replace(filename) + `"`
+}
+
+// splitShell split command line like POSIX shell, support quotes, escapes, line continuation and $” quoting
+func splitShell(s string) ([]string, error) {
+	var (
+		args  []string
+		cur   strings.Builder
+		inArg bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				if s[i] == '\n' {
+					continue
+				}
+				if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+					i++
+					continue
+				}
+				cur.WriteByte(s[i])
+			}
+			inArg = true
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("curl: unterminated quote")
+			}
+			cur.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			i += 2 //nolint:gomnd
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						cur.WriteByte('\n')
+					case 't':
+						cur.WriteByte('\t')
+					case 'r':
+						cur.WriteByte('\r')
+					default:
+						cur.WriteByte(s[i])
+					}
+					continue
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("curl: unterminated quote")
+			}
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+					i++
+					if s[i] == '\n' {
+						continue
+					}
+				}
+				cur.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, errors.New("curl: unterminated quote")
+			}
+			inArg = true
+		default:
+			cur.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
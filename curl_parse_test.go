@@ -0,0 +1,156 @@
+package req_test
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestParseCurl(t *testing.T) {
+	r, err := req.ParseCurl(`curl -sSL -XPUT 'https://api.wener.me/v1/users?a=1' \
+  -H 'Content-Type: application/json' \
+  -H "X-Quote: \"it's\"" \
+  -u user:pass \
+  -d '{"name":"wener"}'`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPut, r.Method)
+	assert.Equal(t, "https://api.wener.me/v1/users", r.URL)
+	assert.Equal(t, "a=1", r.RawQuery)
+	assert.Equal(t, `{"name":"wener"}`, string(r.RawBody))
+	assert.Equal(t, http.Header{
+		"Content-Type":  []string{"application/json"},
+		"X-Quote":       []string{`"it's"`},
+		"Authorization": []string{"Basic dXNlcjpwYXNz"},
+	}, r.Header)
+
+	r, err = req.ParseCurl(`curl api.wener.me/search -G --data-urlencode 'q=a b' -d limit=10 --data-urlencode $'x\ty'`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodGet, r.Method)
+	assert.Equal(t, "http://api.wener.me/search", r.URL)
+	assert.Equal(t, "q=a%20b&limit=10&x%09y", r.RawQuery)
+	assert.Nil(t, r.RawBody)
+
+	r, err = req.ParseCurl(`curl -d a=1 -d b=2 http://wener.me`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "a=1&b=2", string(r.RawBody))
+	assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+
+	r, err = req.ParseCurl(`curl -I http://wener.me`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodHead, r.Method)
+
+	// multipart
+	file := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+	r, err = req.ParseCurl(`curl -F name=wener -F 'file=@` + file + `;type=text/plain' http://wener.me/upload`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, r.Method)
+	mt, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/form-data", mt)
+	mr := multipart.NewReader(strings.NewReader(string(r.RawBody)), params["boundary"])
+	p, err := mr.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "name", p.FormName())
+	b, _ := io.ReadAll(p)
+	assert.Equal(t, "wener", string(b))
+	p, err = mr.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "a.txt", p.FileName())
+	assert.Equal(t, "text/plain", p.Header.Get("Content-Type"))
+	b, _ = io.ReadAll(p)
+	assert.Equal(t, "content", string(b))
+
+	// round trip with Curl
+	src := req.Request{
+		Method:  http.MethodPatch,
+		URL:     "https://wener.me/api?a=1&b=it%27s",
+		Header:  http.Header{"X-A": []string{"1", "2"}, "Content-Type": []string{"application/json"}},
+		RawBody: []byte(`{"a":"it's"}`),
+	}.WithHook(req.TLSHook(&req.TLSOptions{InsecureSkipVerify: true}))
+	cmd, err := src.Curl()
+	assert.NoError(t, err)
+	r, err = req.ParseCurl(cmd)
+	assert.NoError(t, err)
+	again, err := r.Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, cmd, again)
+
+	for _, v := range []string{
+		`wget http://wener.me`,
+		`curl`,
+		`curl -Z http://wener.me`,
+		`curl 'http://wener.me`,
+		`curl -H`,
+	} {
+		_, err = req.ParseCurl(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestParseCurlArgs(t *testing.T) {
+	for _, test := range []struct {
+		cmd    string
+		method string
+		body   string
+	}{
+		{`curl -sXPOST http://wener.me`, http.MethodPost, ""},
+		{`curl -sSLXPUT -d a=1 http://wener.me`, http.MethodPut, "a=1"},
+		{`curl -sd a=1 http://wener.me`, http.MethodPost, "a=1"},
+		{`curl --json '{"a":' --json '1}' http://wener.me`, http.MethodPost, `{"a":1}`},
+		{`curl -d a=1 -d b=2 http://wener.me`, http.MethodPost, "a=1&b=2"},
+	} {
+		r, err := req.ParseCurl(test.cmd)
+		assert.NoError(t, err, test.cmd)
+		assert.Equal(t, test.method, r.Method, test.cmd)
+		assert.Equal(t, test.body, string(r.RawBody), test.cmd)
+	}
+}
+
+func TestParseCurlHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sent []string
+		for _, k := range []string{"X-Empty", "X-Remove", "User-Agent"} {
+			if v, ok := r.Header[k]; ok {
+				sent = append(sent, k+"="+strings.Join(v, ","))
+			}
+		}
+		_, _ = w.Write([]byte(strings.Join(sent, "&")))
+	}))
+	defer server.Close()
+
+	r, err := req.ParseCurl(`curl -H 'X-Remove: 1' -H 'X-Remove:' -H 'X-Empty;' -H 'User-Agent:' ` + server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.Header{"X-Empty": []string{""}, "User-Agent": []string{""}}, r.Header)
+
+	s, _, err := r.FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "X-Empty=", s)
+
+	cmd, err := r.Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl -H User-Agent: -H 'X-Empty;' `+server.URL, cmd)
+}
+
+func TestParseCurlHooks(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+
+	r, err := req.ParseCurl(`curl -k --resolve api.wener.me:443:` + server.Listener.Addr().String() + ` https://api.wener.me/`)
+	assert.NoError(t, err)
+	out, _, err := r.FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "api.wener.me", out)
+}
@@ -0,0 +1,118 @@
+package req_test
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestCurl(t *testing.T) {
+	cmd, err := req.Request{URL: "https://wener.me/api?q=it's"}.Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl 'https://wener.me/api?q=it'\''s'`, cmd)
+
+	cmd, err = req.Request{
+		Method: http.MethodPost,
+		URL:    "https://wener.me/api",
+		Query:  map[string]string{"a": "1"},
+		Header: http.Header{"X-B": []string{"2"}, "Accept": []string{"*/*"}},
+		Body:   map[string]string{"name": "wener"},
+	}.WithHook(req.JSONEncode).Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl -H 'Accept: */*' -H 'Content-Type: application/json;charset=UTF-8' -H 'X-B: 2' --data-raw '{"name":"wener"}' 'https://wener.me/api?a=1'`, cmd)
+
+	cmd, err = req.Request{URL: "http://wener.me/api?a[]=1&b={x}"}.Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl -g 'http://wener.me/api?a[]=1&b={x}'`, cmd)
+
+	cmd, err = req.Request{Method: http.MethodDelete, URL: "http://wener.me/1"}.Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl -X DELETE http://wener.me/1`, cmd)
+
+	cmd, err = req.Request{Method: http.MethodHead, URL: "http://wener.me/1"}.Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl -I http://wener.me/1`, cmd)
+
+	// transport flags
+	cmd, err = req.Request{URL: "https://wener.me"}.WithHook(
+		req.TLSHook(&req.TLSOptions{InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}),
+		req.ProxyHook(&req.ProxyOptions{URL: "http://127.0.0.1:8080"}),
+		req.TransportHook(&req.TransportOptions{DisableHTTP2: true}),
+	).Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl --http1.1 -k --tlsv1.2 -x http://127.0.0.1:8080 https://wener.me`, cmd)
+
+	// transport chain is not built
+	var handled int32
+	counter := req.Hook{Name: "Counter", HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+		atomic.AddInt32(&handled, 1)
+		return next
+	}}
+	cmd, err = req.Request{URL: "https://wener.me"}.WithHook(counter, req.ProxyHook(nil)).Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl --noproxy '*' https://wener.me`, cmd)
+	assert.Equal(t, int32(0), handled)
+
+	// body is never read from file
+	cmd, err = req.Request{Method: http.MethodPost, URL: "http://wener.me", RawBody: []byte("@/etc/passwd")}.Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl --data-raw @/etc/passwd http://wener.me`, cmd)
+
+	cmd, err = req.Request{URL: "https://wener.me/v1"}.WithHook(
+		req.TLSHook(&req.TLSOptions{
+			CertFile: "client.pem", KeyFile: "client.key", RootCAFiles: []string{"ca.pem"},
+			Pins: []string{"sha256/AAAA"}, ServerName: "api.wener.me", RootCAPEM: []byte("-"),
+		}),
+		req.ResolveHook(&req.ResolveOptions{Hosts: map[string][]string{"wener.me": {"127.0.0.1", "::1"}}}),
+	).Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `# unsupported: TLS RootCAPEM, save it for --cacert
+# unsupported: TLS ServerName api.wener.me
+curl --cert client.pem --key client.key --cacert ca.pem --pinnedpubkey sha256//AAAA --resolve 'wener.me:443:127.0.0.1,[::1]' https://wener.me/v1`, cmd)
+
+	cmd, err = req.Request{URL: "http://wener.me:8080"}.WithHook(
+		req.ResolveHook(&req.ResolveOptions{Hosts: map[string][]string{"wener.me:8080": {"backend:9090"}}}),
+	).Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl --connect-to wener.me:8080:backend:9090 http://wener.me:8080`, cmd)
+
+	cmd, err = req.Request{URL: "http://localhost/_ping"}.WithHook(req.UnixSocketHook("/var/run/docker.sock")).Curl()
+	assert.NoError(t, err)
+	assert.Equal(t, `curl --unix-socket /var/run/docker.sock http://localhost/_ping`, cmd)
+
+	// large body
+	dir := t.TempDir()
+	body := bytes.Repeat([]byte("a"), 100)
+	r, err := req.Request{Method: http.MethodPut, URL: "http://wener.me", RawBody: body}.NewRequest()
+	assert.NoError(t, err)
+	cmd, err = req.CurlCommand(r, &req.CurlOptions{MaxInlineBody: 10, BodyDir: dir, Multiline: true})
+	assert.NoError(t, err)
+	lines := strings.Split(cmd, " \\\n  ")
+	assert.Equal(t, []string{"curl", "-X PUT", lines[2], "http://wener.me"}, lines)
+	assert.True(t, strings.HasPrefix(lines[2], "--data-binary @"+dir))
+	saved, err := os.ReadFile(strings.TrimPrefix(lines[2], "--data-binary @"))
+	assert.NoError(t, err)
+	assert.Equal(t, body, saved)
+
+	// body omitted without BodyDir
+	cmd, err = req.CurlCommand(r, &req.CurlOptions{MaxInlineBody: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, "# body of 100 bytes omitted, pipe it to stdin\ncurl -X PUT --data-binary @- http://wener.me", cmd)
+
+	out := &bytes.Buffer{}
+	_, err = req.Request{URL: "http://wener.me"}.WithHook(
+		req.DebugHook(&req.DebugOptions{Curl: true, Out: out}),
+		req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+		})),
+	).Do()
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "curl http://wener.me\n")
+}
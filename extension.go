@@ -17,7 +17,12 @@ type Hook struct {
 	HandleRequest func(next http.RoundTripper) http.RoundTripper
 	HandleOption  func(r *Request, o interface{}) (bool, error)
 	Encode        func(ctx context.Context, body interface{}) ([]byte, error)
+	// EncodeContent encode body and return Content-Type of encoded body, e.g. multipart with boundary
+	EncodeContent func(ctx context.Context, body interface{}) ([]byte, string, error)
 	Decode        func(ctx context.Context, body []byte, out interface{}) error
+
+	// curl flags equivalent to the transport hook, comments for settings curl can not express
+	curl func(r *http.Request) (args, comments []string)
 }
 
 // Extension of Request
@@ -28,7 +33,8 @@ type Extension struct {
 // With more hooks
 func (e *Extension) With(h ...Hook) {
 	e.Hooks = append(h, e.Hooks...)
-	sort.Slice(e.Hooks, func(i, j int) bool {
+	// stable, later added hooks of same Order come first
+	sort.SliceStable(e.Hooks, func(i, j int) bool {
 		// reverse
 		return e.Hooks[i].Order > e.Hooks[j].Order
 	})
@@ -46,12 +52,22 @@ func (e Extension) Decode(ctx context.Context, body []byte, out interface{}) err
 
 // Encode body
 func (e Extension) Encode(ctx context.Context, body interface{}) ([]byte, error) {
+	b, _, err := e.EncodeContent(ctx, body)
+	return b, err
+}
+
+// EncodeContent encode body, Content-Type is empty when encoder not provide it
+func (e Extension) EncodeContent(ctx context.Context, body interface{}) ([]byte, string, error) {
 	for _, v := range e.Hooks {
-		if v.Encode != nil {
-			return v.Encode(ctx, body)
+		switch {
+		case v.EncodeContent != nil:
+			return v.EncodeContent(ctx, body)
+		case v.Encode != nil:
+			b, err := v.Encode(ctx, body)
+			return b, "", err
 		}
 	}
-	return nil, errors.New("no encoder")
+	return nil, "", errors.New("no encoder")
 }
 
 // OnRequest process request
@@ -0,0 +1,180 @@
+package req
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FaultRule matched request and injected fault, faults apply in order of Latency, Timeout, Reset, Status and Truncate
+type FaultRule struct {
+	Host         string        // Host match url host or hostname, empty match any
+	Path         string        // Path match url path by path.Match pattern, empty match any
+	Method       string        // Method empty match any
+	Probability  float64       // Probability to inject for matched request, default 1
+	Calls        []int         // Calls inject only for n-th matched call, start from 1
+	Latency      time.Duration // Latency delay before continue
+	Reset        bool          // Reset fail with connection reset error
+	Timeout      bool          // Timeout wait until request context done or TimeoutAfter, fail with timeout error
+	TimeoutAfter time.Duration // TimeoutAfter max wait of Timeout, default 30s
+	Status       int           // Status reply with status code without sending request
+	Truncate     int           // Truncate response body after n bytes and fail with io.ErrUnexpectedEOF
+}
+
+// FaultOptions options for FaultHook
+type FaultOptions struct {
+	Rules   []FaultRule
+	Disable bool           // Disable turn off injection
+	Random  func() float64 // Random in [0,1) for Probability, default math/rand
+}
+
+type faultTimeoutError struct{}
+
+func (faultTimeoutError) Error() string   { return "req: fault injected timeout" }
+func (faultTimeoutError) Timeout() bool   { return true }
+func (faultTimeoutError) Temporary() bool { return true }
+
+// FaultHook inject faults for chaos testing, first applied rule wins
+func FaultHook(o *FaultOptions) Hook {
+	if o == nil {
+		o = &FaultOptions{}
+	}
+	random := o.Random
+	if random == nil {
+		random = rand.Float64 //nolint:gosec
+	}
+	var mu sync.Mutex
+	calls := make([]int, len(o.Rules))
+	find := func(r *http.Request) *FaultRule {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range o.Rules {
+			rule := &o.Rules[i]
+			if !rule.match(r) {
+				continue
+			}
+			calls[i]++
+			if len(rule.Calls) > 0 && !containsInt(rule.Calls, calls[i]) {
+				continue
+			}
+			if rule.Probability > 0 && random() >= rule.Probability {
+				continue
+			}
+			return rule
+		}
+		return nil
+	}
+	return Hook{
+		Name:  "Fault",
+		Order: -5,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if o.Disable {
+					return next.RoundTrip(r)
+				}
+				rule := find(r)
+				if rule == nil {
+					return next.RoundTrip(r)
+				}
+				return rule.inject(next, r)
+			})
+		},
+	}
+}
+
+func (rule *FaultRule) match(r *http.Request) bool {
+	if rule.Method != "" && !strings.EqualFold(rule.Method, r.Method) {
+		return false
+	}
+	if rule.Host != "" && rule.Host != r.URL.Host && rule.Host != r.URL.Hostname() {
+		return false
+	}
+	if rule.Path != "" {
+		if ok, _ := path.Match(rule.Path, r.URL.Path); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (rule *FaultRule) inject(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	if rule.Latency > 0 {
+		timer := time.NewTimer(rule.Latency)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		}
+	}
+	if rule.Timeout {
+		wait := rule.TimeoutAfter
+		if wait <= 0 {
+			wait = 30 * time.Second //nolint:gomnd
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+		}
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: faultTimeoutError{}}
+	}
+	if rule.Reset {
+		return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	}
+	if rule.Status > 0 {
+		body := http.StatusText(rule.Status)
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", rule.Status, body),
+			StatusCode:    rule.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       r,
+		}, nil
+	}
+	res, err := next.RoundTrip(r)
+	if err == nil && rule.Truncate > 0 {
+		res.Body = &truncatedBody{ReadCloser: res.Body, remain: rule.Truncate}
+	}
+	return res, err
+}
+
+// truncatedBody fail with io.ErrUnexpectedEOF after remain bytes
+type truncatedBody struct {
+	io.ReadCloser
+	remain int
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remain <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > b.remain {
+		p = p[:b.remain]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remain -= n
+	return n, err
+}
+
+func containsInt(s []int, v int) bool {
+	for _, vv := range s {
+		if vv == v {
+			return true
+		}
+	}
+	return false
+}
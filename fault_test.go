@@ -0,0 +1,79 @@
+package req_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestFaultHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	client := req.Request{BaseURL: server.URL}.WithHook(req.FaultHook(&req.FaultOptions{
+		Rules: []req.FaultRule{
+			{Path: "/latency", Latency: 30 * time.Millisecond},
+			{Path: "/reset", Method: http.MethodGet, Reset: true},
+			{Path: "/timeout", Timeout: true},
+			{Path: "/timeout-after", Timeout: true, TimeoutAfter: 20 * time.Millisecond},
+			{Path: "/truncate", Truncate: 4},
+			{Path: "/calls/*", Calls: []int{2, 3}, Status: http.StatusServiceUnavailable},
+			{Host: "127.0.0.1", Path: "/never", Probability: 0.5, Status: http.StatusTeapot},
+		},
+		Random: func() float64 {
+			return 0.7
+		},
+	}))
+
+	start := time.Now()
+	out, _, err := client.With(req.Request{URL: "/latency"}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", out)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(30*time.Millisecond))
+
+	_, err = client.With(req.Request{URL: "/reset"}).Do()
+	assert.True(t, errors.Is(err, syscall.ECONNRESET))
+	_, err = client.With(req.Request{URL: "/reset", Method: http.MethodPost}).Do()
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.With(req.Request{URL: "/timeout", Context: ctx}).Do()
+	var ne net.Error
+	assert.True(t, errors.As(err, &ne) && ne.Timeout())
+
+	// no deadline
+	start = time.Now()
+	_, err = client.With(req.Request{URL: "/timeout-after"}).Do()
+	assert.True(t, errors.As(err, &ne) && ne.Timeout())
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(20*time.Millisecond))
+
+	res, err := client.With(req.Request{URL: "/truncate"}).Do()
+	assert.NoError(t, err)
+	body, err := io.ReadAll(res.Body)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, "0123", string(body))
+
+	var codes []int
+	for i := 0; i < 4; i++ {
+		_, res, err = client.With(req.Request{URL: "/calls/a"}).FetchString()
+		assert.NoError(t, err)
+		codes = append(codes, res.StatusCode)
+	}
+	assert.Equal(t, []int{200, 503, 503, 200}, codes)
+
+	_, res, err = client.With(req.Request{URL: "/never"}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -0,0 +1,149 @@
+package req
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// UseHandler serve request by http.Handler in memory without network
+//
+// Response is returned once handler write header, body is streamed from handler,
+// handler's request context is canceled when request context done or response body closed.
+func UseHandler(h http.Handler) Hook {
+	return Hook{Name: "Handler", Order: -1, HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return serveHandler(h, r)
+		})
+	}}
+}
+
+func serveHandler(h http.Handler, r *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(r.Context())
+	sr := r.Clone(ctx)
+	sr.RequestURI = r.URL.RequestURI()
+	sr.RemoteAddr = "127.0.0.1:1234"
+	sr.Proto, sr.ProtoMajor, sr.ProtoMinor = "HTTP/1.1", 1, 1
+	if sr.Host == "" {
+		sr.Host = r.URL.Host
+	}
+	if r.URL.Scheme == "https" {
+		sr.TLS = &tls.ConnectionState{Version: tls.VersionTLS12, HandshakeComplete: true, ServerName: r.URL.Hostname()}
+	}
+	if sr.Body == nil {
+		sr.Body = http.NoBody
+	}
+
+	pr, pw := io.Pipe()
+	w := &handlerResponseWriter{header: http.Header{}, req: r, pw: pw, ready: make(chan struct{})}
+	w.res = &http.Response{
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Body:          &handlerBody{PipeReader: pr, cancel: cancel},
+		ContentLength: -1,
+		Request:       r,
+	}
+	go func() {
+		<-ctx.Done()
+		pr.CloseWithError(ctx.Err())
+	}()
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				err := fmt.Errorf("req: handler panic: %v", p)
+				w.mu.Lock()
+				if !w.wroteHeader {
+					w.err = err
+					w.wroteHeader = true
+					close(w.ready)
+				}
+				w.mu.Unlock()
+				pw.CloseWithError(err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_ = pw.Close()
+		}()
+		h.ServeHTTP(w, sr)
+	}()
+
+	select {
+	case <-w.ready:
+		if w.err != nil {
+			cancel()
+			return nil, w.err
+		}
+		return w.res, nil
+	case <-r.Context().Done():
+		cancel()
+		return nil, r.Context().Err()
+	}
+}
+
+type handlerBody struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (b *handlerBody) Close() error {
+	b.cancel()
+	return b.PipeReader.Close()
+}
+
+// handlerResponseWriter stream written body to response through pipe
+type handlerResponseWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	req         *http.Request
+	res         *http.Response
+	pw          *io.PipeWriter
+	ready       chan struct{}
+	wroteHeader bool
+	err         error
+}
+
+func (w *handlerResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *handlerResponseWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.res.StatusCode = code
+	w.res.Status = fmt.Sprintf("%d %s", code, http.StatusText(code))
+	w.res.Header = w.header.Clone()
+	if v, err := strconv.ParseInt(w.res.Header.Get("Content-Length"), 10, 64); err == nil {
+		w.res.ContentLength = v
+	}
+	close(w.ready)
+}
+
+func (w *handlerResponseWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	wrote := w.wroteHeader
+	w.mu.Unlock()
+	if !wrote {
+		if w.header.Get("Content-Type") == "" && w.header.Get("Transfer-Encoding") == "" {
+			w.header.Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.req.Method == http.MethodHead {
+		return len(b), nil
+	}
+	return w.pw.Write(b)
+}
+
+// Flush send header, body is not buffered
+func (w *handlerResponseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
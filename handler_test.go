@@ -0,0 +1,93 @@
+package req_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestUseHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Host", r.Host)
+		w.Header().Set("X-TLS", map[bool]string{true: "1", false: "0"}[r.TLS != nil])
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + r.RequestURI + " " + r.Header.Get("X-Req") + " " + string(body)))
+	})
+	streamed := make(chan struct{})
+	canceled := make(chan struct{})
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-streamed:
+			_, _ = w.Write([]byte("second\n"))
+		case <-r.Context().Done():
+			close(canceled)
+		}
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/block", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	client := req.Request{BaseURL: "https://api.wener.me"}.WithHook(req.UseHandler(mux))
+	out, res, err := client.With(req.Request{
+		URL:     "/echo?a=1",
+		Method:  http.MethodPost,
+		RawBody: []byte("body"),
+		Header:  http.Header{"X-Req": []string{"v"}},
+	}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, "POST /echo?a=1 v body", out)
+	assert.Equal(t, "api.wener.me", res.Header.Get("X-Host"))
+	assert.Equal(t, "1", res.Header.Get("X-TLS"))
+
+	_, res, err = client.With(req.Request{URL: "/none"}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// streaming
+	res, err = client.With(req.Request{URL: "/stream"}).Do()
+	assert.NoError(t, err)
+	br := bufio.NewReader(res.Body)
+	line, err := br.ReadString('\n')
+	assert.NoError(t, err)
+	assert.Equal(t, "first\n", line)
+	close(streamed)
+	rest, err := io.ReadAll(br)
+	assert.NoError(t, err)
+	assert.Equal(t, "second\n", string(rest))
+	assert.NoError(t, res.Body.Close())
+
+	// close body cancel handler
+	streamed = make(chan struct{})
+	res, err = client.With(req.Request{URL: "/stream"}).Do()
+	assert.NoError(t, err)
+	assert.NoError(t, res.Body.Close())
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("handler not canceled")
+	}
+
+	_, err = client.With(req.Request{URL: "/panic"}).Do()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = client.With(req.Request{URL: "/block", Context: ctx}).Do()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -0,0 +1,450 @@
+package req
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// HAR HTTP Archive 1.2, see http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog log of HAR
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+// HARCreator creator of HAR
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry request and response pair
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+}
+
+// HARRequest request of HAREntry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse response of HAREntry
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue header or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARCookie cookie of request or response
+type HARCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+// HARPostData request body, Encoding is custom field for binary body
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"_encoding,omitempty"`
+}
+
+// HARContent response body, binary body is base64 encoded
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings timings in milliseconds, -1 when not applicable
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Bytes decoded body
+func (p *HARPostData) Bytes() ([]byte, error) {
+	return decodeHARText(p.Text, p.Encoding)
+}
+
+// Bytes decoded body
+func (c HARContent) Bytes() ([]byte, error) {
+	return decodeHARText(c.Text, c.Encoding)
+}
+
+func decodeHARText(text, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(text)
+	}
+	return []byte(text), nil
+}
+
+func encodeHARText(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+// HARRecorder record traffic as HAR
+type HARRecorder struct {
+	mu  sync.Mutex
+	har HAR
+}
+
+// NewHARRecorder create HARRecorder
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{har: HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "go-req", Version: "1"},
+		Entries: []*HAREntry{},
+	}}}
+}
+
+// HAR snapshot of recorded HAR
+func (h *HARRecorder) HAR() *HAR {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	har := h.har
+	har.Log.Entries = append([]*HAREntry(nil), h.har.Log.Entries...)
+	return &har
+}
+
+// Save recorded HAR as json file
+func (h *HARRecorder) Save(path string) error {
+	b, err := json.MarshalIndent(h.HAR(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644) //nolint:gosec
+}
+
+// Hook record in HandleRequest, response body is read before return
+func (h *HARRecorder) Hook() Hook {
+	return Hook{
+		Name:  "HAR",
+		Order: -3,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return h.roundTrip(next, r)
+			})
+		},
+	}
+}
+
+type harTrace struct {
+	mu                                  sync.Mutex
+	getConn, dnsStart, dnsDone          time.Time
+	connectStart, connectDone           time.Time
+	tlsStart, tlsDone                   time.Time
+	gotConn, wroteRequest, gotFirstByte time.Time
+	remoteAddr                          string
+	reused                              bool
+}
+
+func (t *harTrace) set(p *time.Time) func() {
+	return func() {
+		t.mu.Lock()
+		if p.IsZero() {
+			*p = time.Now()
+		}
+		t.mu.Unlock()
+	}
+}
+
+func (t *harTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) { t.set(&t.getConn)() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.set(&t.gotConn)()
+			t.mu.Lock()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			t.mu.Unlock()
+		},
+		DNSStart:             func(httptrace.DNSStartInfo) { t.set(&t.dnsStart)() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone)() },
+		ConnectStart:         func(string, string) { t.set(&t.connectStart)() },
+		ConnectDone:          func(string, string, error) { t.set(&t.connectDone)() },
+		TLSHandshakeStart:    t.set(&t.tlsStart),
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.set(&t.tlsDone)() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wroteRequest)() },
+		GotFirstResponseByte: t.set(&t.gotFirstByte),
+	}
+}
+
+func harDuration(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() {
+		return -1
+	}
+	return float64(to.Sub(from)) / float64(time.Millisecond)
+}
+
+func (h *HARRecorder) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	body, err := requestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	trace := &harTrace{}
+	start := time.Now()
+	res, err := next.RoundTrip(r.WithContext(httptrace.WithClientTrace(r.Context(), trace.clientTrace())))
+	if err != nil {
+		return res, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	end := time.Now()
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	e := &HAREntry{
+		StartedDateTime: start.Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:            harDuration(start, end),
+		Request:         harRequest(r, body),
+		Response:        harResponse(res, resBody),
+	}
+	t := trace
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if host, _, err := net.SplitHostPort(t.remoteAddr); err == nil {
+		e.ServerIPAddress = host
+	}
+	if t.reused {
+		e.Connection = "reused"
+	}
+	e.Timings = HARTimings{
+		Blocked: -1,
+		DNS:     harDuration(t.dnsStart, t.dnsDone),
+		Connect: harDuration(t.connectStart, t.connectDone),
+		SSL:     harDuration(t.tlsStart, t.tlsDone),
+		Send:    0,
+		Wait:    e.Time,
+		Receive: 0,
+	}
+	if !t.gotConn.IsZero() {
+		firstStart := t.gotConn
+		for _, v := range []time.Time{t.dnsStart, t.connectStart} {
+			if !v.IsZero() && v.Before(firstStart) {
+				firstStart = v
+			}
+		}
+		e.Timings.Blocked = harDuration(t.getConn, firstStart)
+		if t.wroteRequest.After(t.gotConn) {
+			e.Timings.Send = harDuration(t.gotConn, t.wroteRequest)
+		}
+		if !t.gotFirstByte.IsZero() && !t.wroteRequest.IsZero() {
+			e.Timings.Wait = harDuration(t.wroteRequest, t.gotFirstByte)
+			e.Timings.Receive = harDuration(t.gotFirstByte, end)
+		}
+	}
+
+	h.mu.Lock()
+	h.har.Log.Entries = append(h.har.Log.Entries, e)
+	h.mu.Unlock()
+	return res, nil
+}
+
+func harHeaders(h http.Header) []HARNameValue {
+	out := []HARNameValue{}
+	for k, vv := range h {
+		for _, v := range vv {
+			out = append(out, HARNameValue{Name: k, Value: v})
+		}
+	}
+	sortHARNameValues(out)
+	return out
+}
+
+func sortHARNameValues(s []HARNameValue) {
+	sort.SliceStable(s, func(i, j int) bool {
+		return s[i].Name < s[j].Name
+	})
+}
+
+func harRequest(r *http.Request, body []byte) HARRequest {
+	hr := HARRequest{
+		Method:      r.Method,
+		URL:         r.URL.String(),
+		HTTPVersion: r.Proto,
+		Cookies:     []HARCookie{},
+		Headers:     harHeaders(r.Header),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if hr.HTTPVersion == "" {
+		hr.HTTPVersion = "HTTP/1.1"
+	}
+	for _, c := range r.Cookies() {
+		hr.Cookies = append(hr.Cookies, HARCookie{Name: c.Name, Value: c.Value})
+	}
+	for k, vv := range r.URL.Query() {
+		for _, v := range vv {
+			hr.QueryString = append(hr.QueryString, HARNameValue{Name: k, Value: v})
+		}
+	}
+	sortHARNameValues(hr.QueryString)
+	if len(body) > 0 {
+		hr.PostData = &HARPostData{MimeType: r.Header.Get("Content-Type")}
+		hr.PostData.Text, hr.PostData.Encoding = encodeHARText(body)
+	}
+	return hr
+}
+
+func harResponse(res *http.Response, body []byte) HARResponse {
+	hr := HARResponse{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: res.Proto,
+		Cookies:     []HARCookie{},
+		Headers:     harHeaders(res.Header),
+		RedirectURL: res.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+		Content: HARContent{
+			Size:     len(body),
+			MimeType: res.Header.Get("Content-Type"),
+		},
+	}
+	if hr.HTTPVersion == "" {
+		hr.HTTPVersion = "HTTP/1.1"
+	}
+	for _, c := range res.Cookies() {
+		hc := HARCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure}
+		if !c.Expires.IsZero() {
+			hc.Expires = c.Expires.UTC().Format(time.RFC3339)
+		}
+		hr.Cookies = append(hr.Cookies, hc)
+	}
+	if len(body) > 0 {
+		hr.Content.Text, hr.Content.Encoding = encodeHARText(body)
+	}
+	return hr
+}
+
+// LoadHAR load HAR from file
+func LoadHAR(path string) (*HAR, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadHAR(f)
+}
+
+// ReadHAR read HAR json
+func ReadHAR(r io.Reader) (*HAR, error) {
+	h := &HAR{}
+	if err := json.NewDecoder(r).Decode(h); err != nil {
+		return nil, errors.Wrap(err, "decode har")
+	}
+	return h, nil
+}
+
+// Requests convert entries to Request for replay, pseudo and hop-by-hop headers are dropped
+func (h *HAR) Requests() ([]Request, error) {
+	var out []Request
+	for _, e := range h.Log.Entries {
+		r, err := e.Request.Request()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Request convert to Request
+func (hr HARRequest) Request() (Request, error) {
+	r := Request{Method: hr.Method, URL: hr.URL}
+	for _, v := range hr.Headers {
+		switch strings.ToLower(v.Name) {
+		case "host", "content-length", "connection", "transfer-encoding", "accept-encoding":
+			continue
+		}
+		if strings.HasPrefix(v.Name, ":") {
+			continue
+		}
+		if r.Header == nil {
+			r.Header = http.Header{}
+		}
+		r.Header.Add(v.Name, v.Value)
+	}
+	if hr.PostData != nil {
+		b, err := hr.PostData.Bytes()
+		if err != nil {
+			return r, errors.Wrapf(err, "decode body of %v %v", hr.Method, hr.URL)
+		}
+		r.RawBody = b
+		if r.Header.Get("Content-Type") == "" && hr.PostData.MimeType != "" {
+			if _, _, err := mime.ParseMediaType(hr.PostData.MimeType); err == nil {
+				if r.Header == nil {
+					r.Header = http.Header{}
+				}
+				r.Header.Set("Content-Type", hr.PostData.MimeType)
+			}
+		}
+	}
+	return r, nil
+}
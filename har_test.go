@@ -0,0 +1,81 @@
+package req_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestHARRecorder(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s", Path: "/", HttpOnly: true})
+		if r.URL.Path == "/bin" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte{0xff, 0x00})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	rec := req.NewHARRecorder()
+	client := req.Request{BaseURL: server.URL}.WithHook(
+		req.TLSHook(&req.TLSOptions{InsecureSkipVerify: true}),
+		rec.Hook(),
+	)
+	out, _, err := client.With(req.Request{
+		URL:     "/api?b=2&a=1",
+		Method:  http.MethodPost,
+		Header:  http.Header{"Content-Type": []string{"application/json"}, "Cookie": []string{"token=t"}},
+		RawBody: []byte(`{"name":"wener"}`),
+	}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, out)
+	bin, _, err := client.With(req.Request{URL: "/bin"}).FetchBytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, bin)
+
+	h := rec.HAR()
+	assert.Equal(t, "1.2", h.Log.Version)
+	assert.Len(t, h.Log.Entries, 2)
+	e := h.Log.Entries[0]
+	assert.Equal(t, http.MethodPost, e.Request.Method)
+	assert.Equal(t, []req.HARNameValue{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}}, e.Request.QueryString)
+	assert.Equal(t, []req.HARCookie{{Name: "token", Value: "t"}}, e.Request.Cookies)
+	assert.Equal(t, `{"name":"wener"}`, e.Request.PostData.Text)
+	assert.Equal(t, http.StatusOK, e.Response.Status)
+	assert.Equal(t, `{"ok":true}`, e.Response.Content.Text)
+	assert.Equal(t, []req.HARCookie{{Name: "session", Value: "s", Path: "/", HTTPOnly: true}}, e.Response.Cookies)
+	assert.Equal(t, "127.0.0.1", e.ServerIPAddress)
+	assert.GreaterOrEqual(t, e.Timings.Connect, 0.0)
+	assert.GreaterOrEqual(t, e.Timings.SSL, 0.0)
+	assert.GreaterOrEqual(t, e.Timings.Wait, 0.0)
+	assert.Equal(t, "base64", h.Log.Entries[1].Response.Content.Encoding)
+	assert.Equal(t, "reused", h.Log.Entries[1].Connection)
+	assert.Equal(t, -1.0, h.Log.Entries[1].Timings.Connect)
+
+	// replay
+	path := filepath.Join(t.TempDir(), "a.har")
+	assert.NoError(t, rec.Save(path))
+	loaded, err := req.LoadHAR(path)
+	assert.NoError(t, err)
+	b, err := loaded.Log.Entries[1].Response.Content.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, b)
+
+	requests, err := loaded.Requests()
+	assert.NoError(t, err)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, http.MethodPost, requests[0].Method)
+	assert.Equal(t, `{"name":"wener"}`, string(requests[0].RawBody))
+	assert.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+
+	out, _, err = requests[0].WithHook(req.TLSHook(&req.TLSOptions{InsecureSkipVerify: true})).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, out)
+}
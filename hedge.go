@@ -0,0 +1,218 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HedgeStats counters of HedgeHook, read with atomic
+type HedgeStats struct {
+	Requests  int64 // Requests hedgeable requests
+	Hedges    int64 // Hedges extra requests fired
+	HedgeWins int64 // HedgeWins responses won by hedged request
+}
+
+// HedgeOptions options for HedgeHook
+type HedgeOptions struct {
+	Delay      time.Duration // Delay before firing hedge, used until enough latency observed when Percentile set, default 100ms
+	Percentile float64       // Percentile of observed latency as delay, e.g. 0.95
+	MaxHedges  int           // MaxHedges extra requests, default 1
+	Methods    []string      // Methods allow hedging, default GET and HEAD
+	Stats      *HedgeStats   // Stats collect counters
+}
+
+// HedgeHook fire duplicate request when no response within delay, first success response win and others are canceled.
+//
+// Request with body is hedged only when GetBody is available.
+func HedgeHook(o *HedgeOptions) Hook {
+	if o == nil {
+		o = &HedgeOptions{}
+	}
+	h := &hedger{o: *o}
+	if h.o.Delay <= 0 {
+		h.o.Delay = 100 * time.Millisecond //nolint:gomnd
+	}
+	if h.o.MaxHedges <= 0 {
+		h.o.MaxHedges = 1
+	}
+	if h.o.Methods == nil {
+		h.o.Methods = []string{http.MethodGet, http.MethodHead}
+	}
+	if h.o.Stats == nil {
+		h.o.Stats = &HedgeStats{}
+	}
+	return Hook{
+		Name:  "Hedge",
+		Order: -20,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return h.roundTrip(next, r)
+			})
+		},
+	}
+}
+
+type hedgeResult struct {
+	res   *http.Response
+	err   error
+	index int
+	start time.Time
+}
+
+type hedger struct {
+	o         HedgeOptions
+	mu        sync.Mutex
+	latencies []time.Duration
+	cursor    int
+}
+
+const hedgeLatencyWindow = 128
+
+func (h *hedger) hedgeable(r *http.Request) bool {
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+	for _, v := range h.o.Methods {
+		if v == r.Method {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *hedger) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	if !h.hedgeable(r) {
+		return next.RoundTrip(r)
+	}
+	atomic.AddInt64(&h.o.Stats.Requests, 1)
+
+	results := make(chan hedgeResult, h.o.MaxHedges+1)
+	var cancels []context.CancelFunc
+	launch := func() {
+		index := len(cancels)
+		ctx, cancel := context.WithCancel(r.Context())
+		cancels = append(cancels, cancel)
+		rr := r.Clone(ctx)
+		if index > 0 && r.GetBody != nil {
+			body, err := r.GetBody()
+			if err != nil {
+				results <- hedgeResult{err: err, index: index}
+				return
+			}
+			rr.Body = body
+		}
+		start := time.Now()
+		go func() {
+			res, err := next.RoundTrip(rr)
+			results <- hedgeResult{res: res, err: err, index: index, start: start}
+		}()
+	}
+	cancelOthers := func(keep int) {
+		for i, cancel := range cancels {
+			if i != keep {
+				cancel()
+			}
+		}
+	}
+
+	delay := h.delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	launch()
+	inflight := 1
+	var last *hedgeResult
+	for inflight > 0 {
+		select {
+		case <-timer.C:
+			if len(cancels) <= h.o.MaxHedges {
+				atomic.AddInt64(&h.o.Stats.Hedges, 1)
+				launch()
+				inflight++
+				timer.Reset(delay)
+			}
+			continue
+		case v := <-results:
+			inflight--
+			if v.err == nil && v.res.StatusCode < http.StatusInternalServerError {
+				if last != nil && last.res != nil {
+					// held failed response
+					_ = last.res.Body.Close()
+				}
+				cancelOthers(v.index)
+				go drainHedge(results, inflight)
+				h.observe(time.Since(v.start))
+				if v.index > 0 {
+					atomic.AddInt64(&h.o.Stats.HedgeWins, 1)
+				}
+				onBodyClose(v.res, cancels[v.index])
+				return v.res, nil
+			}
+			if last != nil && last.res != nil {
+				_ = last.res.Body.Close()
+				cancels[last.index]()
+			}
+			last = &v
+			// failed fast - hedge now
+			if inflight == 0 && len(cancels) <= h.o.MaxHedges && r.Context().Err() == nil {
+				atomic.AddInt64(&h.o.Stats.Hedges, 1)
+				launch()
+				inflight++
+			}
+		}
+	}
+	if last.res != nil {
+		cancelOthers(last.index)
+		onBodyClose(last.res, cancels[last.index])
+	} else {
+		cancelOthers(-1)
+	}
+	return last.res, last.err
+}
+
+// drainHedge close responses of lost requests
+func drainHedge(results chan hedgeResult, n int) {
+	for i := 0; i < n; i++ {
+		if v := <-results; v.res != nil {
+			_ = v.res.Body.Close()
+		}
+	}
+}
+
+func (h *hedger) observe(d time.Duration) {
+	if h.o.Percentile <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeLatencyWindow {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.cursor] = d
+	h.cursor = (h.cursor + 1) % hedgeLatencyWindow
+}
+
+func (h *hedger) delay() time.Duration {
+	if h.o.Percentile <= 0 {
+		return h.o.Delay
+	}
+	h.mu.Lock()
+	if len(h.latencies) < 20 { //nolint:gomnd
+		h.mu.Unlock()
+		return h.o.Delay
+	}
+	sorted := append([]time.Duration(nil), h.latencies...)
+	h.mu.Unlock()
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	i := int(h.o.Percentile * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
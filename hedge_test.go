@@ -0,0 +1,123 @@
+package req_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestHedgeHook(t *testing.T) {
+	var calls, canceled int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(&calls, 1)%2 == 1 {
+			select {
+			case <-r.Context().Done():
+				atomic.AddInt32(&canceled, 1)
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		_, _ = w.Write([]byte("fast " + string(body)))
+	}))
+	defer server.Close()
+
+	stats := &req.HedgeStats{}
+	client := req.Request{BaseURL: server.URL}.WithHook(req.HedgeHook(&req.HedgeOptions{
+		Delay:   20 * time.Millisecond,
+		Methods: []string{http.MethodGet, http.MethodPut},
+		Stats:   stats,
+	}))
+	start := time.Now()
+	out, _, err := client.FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "fast ", out)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+
+	out, _, err = client.With(req.Request{Method: http.MethodPut, RawBody: []byte("body")}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "fast body", out)
+
+	assert.EqualValues(t, 2, atomic.LoadInt64(&stats.Requests))
+	assert.EqualValues(t, 2, atomic.LoadInt64(&stats.Hedges))
+	assert.EqualValues(t, 2, atomic.LoadInt64(&stats.HedgeWins))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&canceled) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// not hedged
+	atomic.StoreInt32(&calls, 1)
+	out, _, err = client.With(req.Request{Method: http.MethodPost}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "fast ", out)
+	assert.EqualValues(t, 2, atomic.LoadInt64(&stats.Requests))
+}
+
+func TestHedgeHookPercentile(t *testing.T) {
+	var calls, slow int32
+	stats := &req.HedgeStats{}
+	client := req.Request{BaseURL: "http://wener.me"}.WithHook(
+		req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if atomic.LoadInt32(&slow) == 1 {
+				time.Sleep(50 * time.Millisecond)
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK")), Request: r}, nil
+		})),
+		req.HedgeHook(&req.HedgeOptions{Delay: time.Hour, Percentile: 0.9, Stats: stats}),
+	)
+	out, _, err := client.FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "OK", out)
+	// first failed request hedged immediately
+	assert.EqualValues(t, 1, atomic.LoadInt64(&stats.Hedges))
+	assert.EqualValues(t, 1, atomic.LoadInt64(&stats.HedgeWins))
+
+	for i := 0; i < 30; i++ {
+		_, _, err = client.FetchString()
+		assert.NoError(t, err)
+	}
+	// delay of observed latency instead of an hour
+	hedges := atomic.LoadInt64(&stats.Hedges)
+	atomic.StoreInt32(&slow, 1)
+	_, _, err = client.FetchString()
+	assert.NoError(t, err)
+	assert.EqualValues(t, hedges+1, atomic.LoadInt64(&stats.Hedges))
+}
+
+type closeTracker struct {
+	io.Reader
+	closed int32
+}
+
+func (c *closeTracker) Close() error {
+	atomic.StoreInt32(&c.closed, 1)
+	return nil
+}
+
+func TestHedgeHookCloseFailed(t *testing.T) {
+	var calls int32
+	failed := &closeTracker{Reader: strings.NewReader("unavailable")}
+	client := req.Request{BaseURL: "http://wener.me"}.WithHook(
+		req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: failed, Request: r}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK")), Request: r}, nil
+		})),
+		req.HedgeHook(&req.HedgeOptions{Delay: time.Hour}),
+	)
+	out, _, err := client.FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "OK", out)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failed.closed))
+}
@@ -1,18 +1,13 @@
 package req
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/fs"
-	"mime/multipart"
 	"net/http"
 	"net/http/httputil"
 	"os"
-
-	"github.com/pkg/errors"
 )
 
 // JSONEncode encode use json.Marshal, add Content-Type
@@ -55,35 +50,6 @@ var FormEncode = Hook{
 	},
 }
 
-var MultipartFormEncode = Hook{
-	Name: "MultipartFormEncode",
-	OnRequest: func(r *http.Request) (err error) {
-		if r.Body == nil {
-			return errors.New("MultipartFormEncode need file body")
-		}
-		h := multipart.FileHeader{}
-		switch f := r.Body.(type) {
-		// case []fs.File:
-		case fs.File:
-			info, err := f.Stat()
-			if err != nil {
-				return err
-			}
-			h.Filename = info.Name()
-			h.Size = info.Size()
-		default:
-			return errors.Errorf("unsupported file type: %T", r.Body)
-		}
-		r.GetBody = func() (io.ReadCloser, error) {
-			b := &bytes.Buffer{}
-			// writer := multipart.NewWriter(b)
-			// writer.WriteField()
-			return io.NopCloser(b), nil
-		}
-		return
-	},
-}
-
 // DebugOptions options for DebugHook
 type DebugOptions struct {
 	Disable   bool                        // Disable turn off debug
@@ -91,6 +57,7 @@ type DebugOptions struct {
 	Out       io.Writer                   // Out debug output, default stderr
 	ErrorOnly bool                        // ErrorOnly enable dump error only
 	IsError   func(r *http.Response) bool // IsError check if response is error, default is http.StatusOK < 400
+	Curl      bool                        // Curl print request as curl command instead of dump
 }
 
 // DebugHook dump http.Request and http.Response
@@ -105,7 +72,16 @@ func DebugHook(o *DebugOptions) Hook {
 		Name:  "Debug",
 		Order: -100,
 		OnRequest: func(r *http.Request) error {
-			if !o.Disable {
+			switch {
+			case o.Disable:
+			case o.Curl:
+				cmd, err := CurlCommand(r, nil)
+				if err != nil {
+					cmd = err.Error()
+				}
+				_, _ = fmt.Fprintln(o.Out, "->", r.Method, r.URL)
+				_, _ = fmt.Fprintln(o.Out, cmd)
+			default:
 				dump, _ := httputil.DumpRequestOut(r, o.Body)
 				_, _ = fmt.Fprintln(o.Out, "->", r.Method, r.URL)
 				_, _ = fmt.Fprintln(o.Out, string(dump))
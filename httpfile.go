@@ -0,0 +1,517 @@
+package req
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPFile parsed JetBrains or VS Code REST Client .http file
+type HTTPFile struct {
+	Dir       string            // Dir of file, used to resolve body file
+	Variables map[string]string // Variables defined by @name = value
+	Requests  []*HTTPFileRequest
+}
+
+// HTTPFileRequest request of HTTPFile, values are not interpolated
+type HTTPFileRequest struct {
+	Name     string // Name by # @name or ### title
+	Line     int    // Line of request line
+	Method   string
+	URL      string
+	Header   http.Header
+	Body     string
+	BodyFile string            // BodyFile by < path
+	Captures []HTTPFileCapture // Captures by client.global.set in response handler
+}
+
+// HTTPFileCapture capture value from response as global variable
+type HTTPFileCapture struct {
+	Name string
+	Expr string // Expr e.g. response.body.token, response.headers.valueOf("X-Token"), response.status
+}
+
+var (
+	httpFileMethods = map[string]bool{
+		http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true,
+		http.MethodDelete: true, http.MethodOptions: true, http.MethodTrace: true, http.MethodConnect: true,
+	}
+	httpFileVarRegex     = regexp.MustCompile(`\{\{\s*(.+?)\s*\}\}`)
+	httpFileCaptureRegex = regexp.MustCompile(`client\.global\.set\(\s*["']([^"']+)["']\s*,\s*(.+?)\s*\)\s*;?\s*$`)
+)
+
+// LoadHTTPFile load and parse .http file
+func LoadHTTPFile(path string) (*HTTPFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hf, err := ParseHTTPFile(f)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	hf.Dir = filepath.Dir(path)
+	return hf, nil
+}
+
+// ParseHTTPFile parse .http file, requests are separated by ###
+func ParseHTTPFile(r io.Reader) (*HTTPFile, error) {
+	f := &HTTPFile{Variables: map[string]string{}}
+	const (
+		stateNone = iota
+		stateHeader
+		stateBody
+		stateHandler
+	)
+	var (
+		state   = stateNone
+		title   string
+		cur     *HTTPFileRequest
+		body    []string
+		handler strings.Builder
+	)
+	finish := func() {
+		if cur != nil {
+			for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+				body = body[:len(body)-1]
+			}
+			cur.Body = strings.Join(body, "\n")
+			if cur.Name == "" {
+				cur.Name = title
+			}
+			f.Requests = append(f.Requests, cur)
+		}
+		cur, body, state, title = nil, nil, stateNone, ""
+	}
+	name := ""
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for ln := 1; sc.Scan(); ln++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "###") && state != stateHandler {
+			finish()
+			title = strings.TrimSpace(strings.TrimPrefix(trimmed, "###"))
+			continue
+		}
+		switch state {
+		case stateNone:
+			switch {
+			case trimmed == "":
+			case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//"):
+				c := strings.TrimSpace(strings.TrimLeft(trimmed, "#/"))
+				if strings.HasPrefix(c, "@name") {
+					name = strings.TrimSpace(strings.TrimLeft(strings.TrimPrefix(c, "@name"), " ="))
+				}
+			case strings.HasPrefix(trimmed, "@"):
+				k, v, ok := cutString(trimmed[1:], "=")
+				if !ok {
+					return nil, errors.Errorf("line %d: invalid variable %q", ln, trimmed)
+				}
+				f.Variables[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			default:
+				cur = &HTTPFileRequest{Name: name, Line: ln, Method: http.MethodGet, Header: http.Header{}}
+				name = ""
+				fields := strings.Fields(trimmed)
+				if httpFileMethods[fields[0]] {
+					cur.Method = fields[0]
+					fields = fields[1:]
+				}
+				if len(fields) > 0 && strings.HasPrefix(fields[len(fields)-1], "HTTP/") {
+					fields = fields[:len(fields)-1]
+				}
+				if len(fields) != 1 {
+					return nil, errors.Errorf("line %d: invalid request line %q", ln, trimmed)
+				}
+				cur.URL = fields[0]
+				state = stateHeader
+			}
+		case stateHeader:
+			switch {
+			case trimmed == "":
+				state = stateBody
+			case len(cur.Header) == 0 && (strings.HasPrefix(trimmed, "?") || strings.HasPrefix(trimmed, "&")):
+				cur.URL += trimmed
+			case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//"):
+			default:
+				k, v, ok := cutString(trimmed, ":")
+				if !ok {
+					return nil, errors.Errorf("line %d: invalid header %q", ln, trimmed)
+				}
+				cur.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+			}
+		case stateBody:
+			switch {
+			case strings.HasPrefix(trimmed, "> {%"):
+				handler.Reset()
+				handler.WriteString(strings.TrimPrefix(trimmed, "> {%"))
+				state = stateHandler
+			case strings.HasPrefix(trimmed, ">"):
+				return nil, errors.Errorf("line %d: response handler file is not supported", ln)
+			case strings.HasPrefix(trimmed, "<>"):
+				// previous response reference
+			case strings.HasPrefix(trimmed, "< ") && len(body) == 0:
+				cur.BodyFile = strings.TrimSpace(trimmed[2:])
+			default:
+				body = append(body, line)
+			}
+		case stateHandler:
+			handler.WriteString("\n")
+			handler.WriteString(line)
+		}
+		if state == stateHandler && strings.Contains(handler.String(), "%}") {
+			script := handler.String()
+			script = script[:strings.Index(script, "%}")]
+			for _, stmt := range strings.FieldsFunc(script, func(r rune) bool { return r == '\n' || r == ';' }) {
+				if m := httpFileCaptureRegex.FindStringSubmatch(strings.TrimSpace(stmt)); m != nil {
+					cur.Captures = append(cur.Captures, HTTPFileCapture{Name: m[1], Expr: m[2]})
+				}
+			}
+			state = stateBody
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if state == stateHandler {
+		return nil, errors.New("unterminated response handler")
+	}
+	finish()
+	return f, nil
+}
+
+// LoadHTTPEnv load environment from http-client.env.json style file, $shared and
+// the private env file next to it are merged
+func LoadHTTPEnv(path string, env string) (map[string]string, error) {
+	out := map[string]string{}
+	paths := []string{path}
+	if ext := filepath.Ext(path); strings.HasSuffix(path, ".env"+ext) {
+		paths = append(paths, strings.TrimSuffix(path, ".env"+ext)+".private.env"+ext)
+	}
+	for i, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			if i > 0 && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		all := map[string]map[string]interface{}{}
+		if err = json.Unmarshal(b, &all); err != nil {
+			return nil, errors.Wrapf(err, "parse env %v", p)
+		}
+		for _, name := range []string{"$shared", env} {
+			for k, v := range all[name] {
+				out[k] = jsonString(v)
+			}
+		}
+	}
+	return out, nil
+}
+
+// HTTPFileResult result of executed HTTPFileRequest
+type HTTPFileResult struct {
+	Request  *HTTPFileRequest
+	Response *http.Response
+	Body     []byte
+}
+
+// HTTPFileRunner execute requests of HTTPFile in order, captured values are available for later requests
+type HTTPFileRunner struct {
+	File  *HTTPFile
+	Env   map[string]string           // Env variables of selected environment
+	Vars  map[string]string           // Vars global variables, captured values are stored here
+	Base  Request                     // Base of built Request, e.g. with hooks
+	Check func(*HTTPFileResult) error // Check fail run when return error, e.g. unexpected status
+
+	responses map[string]*HTTPFileResult
+}
+
+// NewHTTPFileRunner create HTTPFileRunner
+func NewHTTPFileRunner(f *HTTPFile, env map[string]string) *HTTPFileRunner {
+	return &HTTPFileRunner{File: f, Env: env, Vars: map[string]string{}}
+}
+
+// Run execute all requests, stop at first error
+func (hr *HTTPFileRunner) Run() ([]*HTTPFileResult, error) {
+	var out []*HTTPFileResult
+	for _, v := range hr.File.Requests {
+		res, err := hr.RunRequest(v)
+		if err != nil {
+			return out, errors.Wrapf(err, "request %q at line %d", v.Name, v.Line)
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// RunRequest execute single request and capture values
+func (hr *HTTPFileRunner) RunRequest(v *HTTPFileRequest) (*HTTPFileResult, error) {
+	r, err := hr.Request(v)
+	if err != nil {
+		return nil, err
+	}
+	body, res, err := r.FetchBytes()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	result := &HTTPFileResult{Request: v, Response: res, Body: body}
+	if hr.responses == nil {
+		hr.responses = map[string]*HTTPFileResult{}
+	}
+	if v.Name != "" {
+		hr.responses[v.Name] = result
+	}
+	if hr.Vars == nil {
+		hr.Vars = map[string]string{}
+	}
+	for _, c := range v.Captures {
+		val, err := evalHTTPFileExpr(result, c.Expr)
+		if err != nil {
+			return result, errors.Wrapf(err, "capture %v", c.Name)
+		}
+		hr.Vars[c.Name] = val
+	}
+	if hr.Check != nil {
+		if err = hr.Check(result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// Request build Request with interpolated values
+func (hr *HTTPFileRunner) Request(v *HTTPFileRequest) (Request, error) {
+	var err error
+	r := Request{Method: v.Method, Header: http.Header{}}
+	if r.URL, err = hr.Interpolate(v.URL); err != nil {
+		return r, err
+	}
+	if u, q, ok := cutString(r.URL, "?"); ok {
+		r.URL = u + "?" + escapeRawQuery(q)
+	}
+	for k, vv := range v.Header {
+		for _, s := range vv {
+			if s, err = hr.Interpolate(s); err != nil {
+				return r, err
+			}
+			r.Header.Add(k, s)
+		}
+	}
+	switch {
+	case v.BodyFile != "":
+		p := v.BodyFile
+		if !filepath.IsAbs(p) && hr.File.Dir != "" {
+			p = filepath.Join(hr.File.Dir, p)
+		}
+		if r.RawBody, err = os.ReadFile(p); err != nil {
+			return r, err
+		}
+	case v.Body != "":
+		body, err := hr.Interpolate(v.Body)
+		if err != nil {
+			return r, err
+		}
+		r.RawBody = []byte(body)
+	}
+	return hr.Base.With(r), nil
+}
+
+// Interpolate replace {{var}} in s
+func (hr *HTTPFileRunner) Interpolate(s string) (string, error) {
+	return hr.interpolate(s, 0)
+}
+
+func (hr *HTTPFileRunner) interpolate(s string, depth int) (string, error) {
+	if depth > 10 { //nolint:gomnd
+		return "", errors.Errorf("variable recursion too deep: %v", s)
+	}
+	var err error
+	out := httpFileVarRegex.ReplaceAllStringFunc(s, func(m string) string {
+		if err != nil {
+			return m
+		}
+		var v string
+		v, err = hr.lookup(httpFileVarRegex.FindStringSubmatch(m)[1], depth)
+		return v
+	})
+	return out, err
+}
+
+func (hr *HTTPFileRunner) lookup(name string, depth int) (string, error) {
+	if strings.HasPrefix(name, "$") {
+		return httpFileDynamic(name)
+	}
+	if v, ok := hr.Vars[name]; ok {
+		return v, nil
+	}
+	if v, ok := hr.Env[name]; ok {
+		return hr.interpolate(v, depth+1)
+	}
+	if v, ok := hr.File.Variables[name]; ok {
+		return hr.interpolate(v, depth+1)
+	}
+	// {{name.response.body.$.token}} of VS Code REST Client
+	if reqName, expr, ok := cutString(name, ".response."); ok {
+		res, found := hr.responses[reqName]
+		if !found {
+			return "", errors.Errorf("request %q not executed", reqName)
+		}
+		if strings.HasPrefix(expr, "headers.") {
+			return res.Response.Header.Get(strings.TrimPrefix(expr, "headers.")), nil
+		}
+		expr = strings.Replace(expr, "body.$", "body", 1)
+		return evalHTTPFileExpr(res, "response."+expr)
+	}
+	return "", errors.Errorf("undefined variable %q", name)
+}
+
+// escapeRawQuery escape interpolated query keep order of parameters
+func escapeRawQuery(q string) string {
+	q, fragment, hasFragment := cutString(q, "#")
+	parts := strings.Split(q, "&")
+	for i, p := range parts {
+		k, v, ok := cutString(p, "=")
+		if uk, err := url.QueryUnescape(k); err == nil {
+			k = uk
+		}
+		if uv, err := url.QueryUnescape(v); err == nil {
+			v = uv
+		}
+		parts[i] = url.QueryEscape(k)
+		if ok {
+			parts[i] += "=" + url.QueryEscape(v)
+		}
+	}
+	q = strings.Join(parts, "&")
+	if hasFragment {
+		q += "#" + fragment
+	}
+	return q
+}
+
+func httpFileDynamic(name string) (string, error) {
+	fields := strings.Fields(name)
+	switch fields[0] {
+	case "$uuid", "$random.uuid":
+		return newUUID(), nil
+	case "$timestamp":
+		return strconv.FormatInt(time.Now().Unix(), 10), nil
+	case "$isoTimestamp":
+		return time.Now().UTC().Format(time.RFC3339), nil
+	case "$randomInt", "$random.integer":
+		n, err := rand.Int(rand.Reader, big.NewInt(1000)) //nolint:gomnd
+		if err != nil {
+			return "", err
+		}
+		return n.String(), nil
+	case "$processEnv":
+		if len(fields) != 2 {
+			return "", errors.Errorf("invalid %v", name)
+		}
+		return os.Getenv(fields[1]), nil
+	}
+	if strings.HasPrefix(fields[0], "$env.") {
+		return os.Getenv(strings.TrimPrefix(fields[0], "$env.")), nil
+	}
+	return "", errors.Errorf("unsupported dynamic variable %v", name)
+}
+
+// evalHTTPFileExpr evaluate response.status, response.headers.valueOf("name"), response.body.path or string literal
+func evalHTTPFileExpr(res *HTTPFileResult, expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if len(expr) >= 2 && (expr[0] == '"' || expr[0] == '\'') && expr[len(expr)-1] == expr[0] {
+		return expr[1 : len(expr)-1], nil
+	}
+	switch {
+	case expr == "response.status":
+		return strconv.Itoa(res.Response.StatusCode), nil
+	case strings.HasPrefix(expr, "response.headers.valueOf("):
+		name := strings.Trim(strings.TrimSuffix(strings.TrimPrefix(expr, "response.headers.valueOf("), ")"), `"' `)
+		return res.Response.Header.Get(name), nil
+	case expr == "response.body":
+		return string(res.Body), nil
+	case strings.HasPrefix(expr, "response.body.") || strings.HasPrefix(expr, "response.body["):
+		var v interface{}
+		if err := json.Unmarshal(res.Body, &v); err != nil {
+			return "", errors.Wrap(err, "response body is not json")
+		}
+		v, err := jsonPathValue(v, strings.TrimPrefix(strings.TrimPrefix(expr, "response.body"), "."))
+		if err != nil {
+			return "", err
+		}
+		return jsonString(v), nil
+	}
+	return "", errors.Errorf("unsupported expression %v", expr)
+}
+
+// jsonPathValue select value by path like a.b[0]["c"]
+func jsonPathValue(v interface{}, path string) (interface{}, error) {
+	for path != "" {
+		var key string
+		switch {
+		case strings.HasPrefix(path, "["):
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, errors.Errorf("invalid path %v", path)
+			}
+			key, path = path[1:end], path[end+1:]
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			key, path = path[:end], path[end:]
+		}
+		path = strings.TrimPrefix(path, ".")
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			v = vv[strings.Trim(key, `"'`)]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(vv) {
+				return nil, errors.Errorf("invalid index %v", key)
+			}
+			v = vv[i]
+		default:
+			return nil, errors.Errorf("can not select %v of %T", key, v)
+		}
+	}
+	return v, nil
+}
+
+func jsonString(v interface{}) string {
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case nil:
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func newUUID() string {
+	b := make([]byte, 16) //nolint:gomnd
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,150 @@
+package req_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+const testHTTPFile = `@user = wener
+@greeting = hello {{user}}
+
+### Login
+POST {{host}}/login HTTP/1.1
+Content-Type: application/json
+
+{"user":"{{user}}","id":"{{$uuid}}"}
+
+> {%
+  client.global.set("token", response.body.data.token);
+  client.global.set("trace", response.headers.valueOf("X-Trace"));
+%}
+
+###
+# @name me
+GET {{host}}/me
+  ?greeting={{greeting}}
+  &env={{env}}
+Authorization: Bearer {{token}}
+
+###
+// @name upload
+PUT {{host}}/upload
+Content-Type: text/plain
+
+< ./body.txt
+
+### Echo
+POST {{host}}/echo
+
+{{me.response.body.$.items[1]}} {{me.response.headers.X-Trace}}
+`
+
+func TestHTTPFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Trace", "trace-"+r.URL.Path)
+		switch r.URL.Path {
+		case "/login":
+			var in map[string]string
+			_ = json.Unmarshal(body, &in)
+			if in["user"] != "wener" || len(in["id"]) != 36 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{"token":"t1"}}`))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer t1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"items": []interface{}{r.URL.Query().Get("greeting"), r.URL.Query().Get("env")},
+			})
+		default:
+			_, _ = w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.http")
+	assert.NoError(t, os.WriteFile(path, []byte(testHTTPFile), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "body.txt"), []byte("file {{body}}"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "http-client.env.json"), []byte(`{
+  "$shared": {"env": "shared"},
+  "dev": {"host": "http://dev", "env": "dev"}
+}`), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "http-client.private.env.json"), []byte(`{"dev": {"host": "`+server.URL+`"}}`), 0o600))
+
+	f, err := req.LoadHTTPFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"user": "wener", "greeting": "hello {{user}}"}, f.Variables)
+	assert.Len(t, f.Requests, 4)
+	assert.Equal(t, "Login", f.Requests[0].Name)
+	assert.Equal(t, 5, f.Requests[0].Line)
+	assert.Equal(t, []req.HTTPFileCapture{
+		{Name: "token", Expr: "response.body.data.token"},
+		{Name: "trace", Expr: `response.headers.valueOf("X-Trace")`},
+	}, f.Requests[0].Captures)
+	assert.Equal(t, "me", f.Requests[1].Name)
+	assert.Equal(t, "{{host}}/me?greeting={{greeting}}&env={{env}}", f.Requests[1].URL)
+	assert.Equal(t, "./body.txt", f.Requests[2].BodyFile)
+
+	env, err := req.LoadHTTPEnv(filepath.Join(dir, "http-client.env.json"), "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"host": server.URL, "env": "dev"}, env)
+
+	runner := req.NewHTTPFileRunner(f, env)
+	runner.Check = func(r *req.HTTPFileResult) error {
+		if r.Response.StatusCode >= 400 {
+			return errors.Errorf("status %v", r.Response.StatusCode)
+		}
+		return nil
+	}
+	results, err := runner.Run()
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	assert.Equal(t, "t1", runner.Vars["token"])
+	assert.Equal(t, "trace-/login", runner.Vars["trace"])
+	assert.Equal(t, "file {{body}}", string(results[2].Body))
+	assert.Equal(t, "dev trace-/me", string(results[3].Body))
+	assert.Equal(t, `{"items":["hello wener","dev"]}`, strings.TrimSpace(string(results[1].Body)))
+
+	// failed check
+	runner = req.NewHTTPFileRunner(&req.HTTPFile{Requests: f.Requests[1:2], Variables: f.Variables}, env)
+	runner.Vars["token"] = "bad"
+	runner.Check = func(r *req.HTTPFileResult) error {
+		if r.Response.StatusCode >= 400 {
+			return errors.Errorf("status %v", r.Response.StatusCode)
+		}
+		return nil
+	}
+	_, err = runner.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "status 401")
+
+	_, err = req.NewHTTPFileRunner(f, nil).Request(f.Requests[0])
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `undefined variable "host"`)
+
+	for _, v := range []string{
+		"@a\nGET /",
+		"GET / a b",
+		"GET /\nInvalid",
+		"GET /\n\n> handler.js",
+		"GET /\n\n> {% client.global.set('a', 1)",
+	} {
+		_, err = req.ParseHTTPFile(strings.NewReader(v))
+		assert.Error(t, err, v)
+	}
+}
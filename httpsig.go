@@ -0,0 +1,570 @@
+package req
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrSignatureMismatch returned when signature or digest verification failed
+var ErrSignatureMismatch = errors.New("req: signature mismatch")
+
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// ContentDigestHook add RFC 9530 Content-Digest of encoded body, support sha-256 and sha-512, default sha-256
+func ContentDigestHook(algorithms ...string) Hook {
+	if len(algorithms) == 0 {
+		algorithms = []string{"sha-256"}
+	}
+	return Hook{
+		Name:  "ContentDigest",
+		Order: -5,
+		OnRequest: func(r *http.Request) error {
+			body, err := requestBody(r)
+			if err != nil || body == nil {
+				return err
+			}
+			digest, err := contentDigest(body, algorithms)
+			if err != nil {
+				return err
+			}
+			r.Header.Set("Content-Digest", digest)
+			return nil
+		},
+	}
+}
+
+func contentDigest(body []byte, algorithms []string) (string, error) {
+	var items []string
+	for _, v := range algorithms {
+		h, ok := digestAlgorithms[v]
+		if !ok {
+			return "", errors.Errorf("unsupported digest algorithm %q", v)
+		}
+		d := h()
+		d.Write(body)
+		items = append(items, v+"=:"+base64.StdEncoding.EncodeToString(d.Sum(nil))+":")
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// VerifyContentDigest verify Content-Digest of http.Response, body is restored for later read
+func VerifyContentDigest(r *http.Response) error {
+	header := r.Header.Get("Content-Digest")
+	if header == "" {
+		return errors.New("missing Content-Digest")
+	}
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	verified := false
+	for _, v := range splitSFList(header, ',') {
+		k, val, _ := cutString(v, "=")
+		h, ok := digestAlgorithms[strings.TrimSpace(k)]
+		if !ok {
+			continue
+		}
+		expected, err := decodeSFBytes(val)
+		if err != nil {
+			return err
+		}
+		d := h()
+		d.Write(body)
+		if !hmac.Equal(d.Sum(nil), expected) {
+			return errors.Wrapf(ErrSignatureMismatch, "Content-Digest %s", k)
+		}
+		verified = true
+	}
+	if !verified {
+		return errors.Errorf("no supported algorithm in Content-Digest: %s", header)
+	}
+	return nil
+}
+
+// HTTPSigner sign RFC 9421 signature base
+type HTTPSigner interface {
+	Algorithm() string
+	Sign(data []byte) ([]byte, error)
+}
+
+// HTTPVerifier verify RFC 9421 signature
+type HTTPVerifier interface {
+	Algorithm() string
+	Verify(data []byte, sig []byte) error
+}
+
+// HTTPSignatureOptions options for HTTPSignatureHook
+type HTTPSignatureOptions struct {
+	Label      string           // Label of signature, default sig1
+	KeyID      string           // KeyID param
+	Signer     HTTPSigner       // Signer of signature base
+	Components []string         // Components covered, default @method @target-uri and content-digest, content-type when present
+	Expires    time.Duration    // Expires add expires param when > 0
+	Nonce      func() string    // Nonce add nonce param
+	Tag        string           // Tag param
+	Now        func() time.Time // Now for created, default time.Now
+}
+
+// HTTPSignatureHook sign request by RFC 9421, add Signature-Input and Signature header
+func HTTPSignatureHook(o *HTTPSignatureOptions) Hook {
+	return Hook{
+		Name:  "HTTPSignature",
+		Order: -10,
+		OnRequest: func(r *http.Request) error {
+			if o == nil || o.Signer == nil {
+				return errors.New("HTTPSignatureHook: missing signer")
+			}
+			label := o.Label
+			if label == "" {
+				label = "sig1"
+			}
+			components := o.Components
+			if components == nil {
+				components = []string{"@method", "@target-uri"}
+				for _, v := range []string{"content-digest", "content-type"} {
+					if r.Header.Get(v) != "" {
+						components = append(components, v)
+					}
+				}
+			}
+			now := time.Now
+			if o.Now != nil {
+				now = o.Now
+			}
+			created := now()
+
+			sb := strings.Builder{}
+			sb.WriteString("(")
+			for i, v := range components {
+				if i > 0 {
+					sb.WriteString(" ")
+				}
+				sb.WriteString(serializeComponent(v))
+			}
+			sb.WriteString(");created=")
+			sb.WriteString(strconv.FormatInt(created.Unix(), 10))
+			if o.Expires > 0 {
+				sb.WriteString(";expires=")
+				sb.WriteString(strconv.FormatInt(created.Add(o.Expires).Unix(), 10))
+			}
+			if o.Nonce != nil {
+				sb.WriteString(`;nonce="` + o.Nonce() + `"`)
+			}
+			if o.KeyID != "" {
+				sb.WriteString(`;keyid="` + o.KeyID + `"`)
+			}
+			sb.WriteString(`;alg="` + o.Signer.Algorithm() + `"`)
+			if o.Tag != "" {
+				sb.WriteString(`;tag="` + o.Tag + `"`)
+			}
+			params := sb.String()
+
+			base, err := signatureBase(components, params, func(name string) (string, error) {
+				return requestComponent(r, name)
+			})
+			if err != nil {
+				return err
+			}
+			sig, err := o.Signer.Sign([]byte(base))
+			if err != nil {
+				return err
+			}
+			r.Header.Set("Signature-Input", label+"="+params)
+			r.Header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+			return nil
+		},
+	}
+}
+
+// HTTPVerifierResolver resolve verifier by keyid and alg params of signature
+type HTTPVerifierResolver func(keyID string, alg string) (HTTPVerifier, error)
+
+// VerifyResponseSignature verify all RFC 9421 signatures of http.Response
+func VerifyResponseSignature(r *http.Response, resolve HTTPVerifierResolver) error {
+	return verifySignatures(r.Header, resolve, func(name string) (string, error) {
+		if name == "@status" {
+			return strconv.Itoa(r.StatusCode), nil
+		}
+		if strings.HasPrefix(name, "@") {
+			return "", errors.Errorf("unsupported response component %q", name)
+		}
+		return headerComponent(r.Header, name)
+	})
+}
+
+// VerifyRequestSignature verify all RFC 9421 signatures of http.Request
+func VerifyRequestSignature(r *http.Request, resolve HTTPVerifierResolver) error {
+	return verifySignatures(r.Header, resolve, func(name string) (string, error) {
+		return requestComponent(r, name)
+	})
+}
+
+// HTTPSignatureVerifyHook verify response Content-Digest when present and RFC 9421 signatures
+func HTTPSignatureVerifyHook(resolve HTTPVerifierResolver) Hook {
+	return Hook{
+		Name: "HTTPSignatureVerify",
+		OnResponse: func(r *http.Response) error {
+			if r.Header.Get("Content-Digest") != "" {
+				if err := VerifyContentDigest(r); err != nil {
+					return err
+				}
+			}
+			return VerifyResponseSignature(r, resolve)
+		},
+	}
+}
+
+func verifySignatures(h http.Header, resolve HTTPVerifierResolver, component func(name string) (string, error)) error {
+	inputs := parseSFDictionary(h.Get("Signature-Input"))
+	signatures := parseSFDictionary(h.Get("Signature"))
+	if len(inputs) == 0 {
+		return errors.New("missing Signature-Input")
+	}
+	for label, params := range inputs {
+		sig, err := decodeSFBytes(signatures[label])
+		if err != nil {
+			return errors.Wrapf(err, "signature %s", label)
+		}
+		components, p, err := parseSignatureParams(params)
+		if err != nil {
+			return errors.Wrapf(err, "signature %s", label)
+		}
+		if v := p["expires"]; v != "" {
+			expires, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return errors.Wrapf(err, "signature %s expires", label)
+			}
+			if time.Now().Unix() > expires {
+				return errors.Errorf("signature %s expired", label)
+			}
+		}
+		verifier, err := resolve(p["keyid"], p["alg"])
+		if err != nil {
+			return err
+		}
+		if alg := p["alg"]; alg != "" && alg != verifier.Algorithm() {
+			return errors.Errorf("signature %s algorithm mismatch: %s", label, alg)
+		}
+		base, err := signatureBase(components, params, component)
+		if err != nil {
+			return err
+		}
+		if err = verifier.Verify([]byte(base), sig); err != nil {
+			return errors.Wrapf(ErrSignatureMismatch, "signature %s: %v", label, err)
+		}
+	}
+	return nil
+}
+
+func signatureBase(components []string, params string, component func(name string) (string, error)) (string, error) {
+	sb := strings.Builder{}
+	for _, v := range components {
+		value, err := component(v)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(serializeComponent(v))
+		sb.WriteString(": ")
+		sb.WriteString(value)
+		sb.WriteString("\n")
+	}
+	sb.WriteString(`"@signature-params": `)
+	sb.WriteString(params)
+	return sb.String(), nil
+}
+
+// serializeComponent quote component name, keep params - @query-param;name="a" to "@query-param";name="a"
+func serializeComponent(v string) string {
+	name, params, ok := cutString(v, ";")
+	if ok {
+		return strconv.Quote(name) + ";" + params
+	}
+	return strconv.Quote(name)
+}
+
+func requestComponent(r *http.Request, v string) (string, error) {
+	name, params, _ := cutString(v, ";")
+	switch name {
+	case "@method":
+		return r.Method, nil
+	case "@target-uri":
+		u := *r.URL
+		if u.Host == "" {
+			u.Host = r.Host
+		}
+		u.Scheme = requestScheme(r)
+		return u.String(), nil
+	case "@authority":
+		return strings.ToLower(requestHeader(r, "Host")), nil
+	case "@scheme":
+		return requestScheme(r), nil
+	case "@request-target":
+		return r.URL.RequestURI(), nil
+	case "@path":
+		if p := r.URL.EscapedPath(); p != "" {
+			return p, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + r.URL.RawQuery, nil
+	case "@query-param":
+		// name param is in encoded form, e.g. name="fa%C3%A7ade"
+		param, err := url.QueryUnescape(parseSFParams(params)["name"])
+		if err != nil {
+			return "", errors.Wrap(err, "invalid query param name")
+		}
+		if q, ok := r.URL.Query()[param]; ok && len(q) > 0 {
+			return queryParamEscape(q[0]), nil
+		}
+		return "", errors.Errorf("missing query param %q", param)
+	}
+	if strings.HasPrefix(name, "@") {
+		return "", errors.Errorf("unsupported request component %q", name)
+	}
+	return headerComponent(r.Header, name)
+}
+
+// queryParamEscape percent-encode with application/x-www-form-urlencoded set, space is %20 instead of +
+func queryParamEscape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("*-._", c) >= 0 {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&15])
+	}
+	return sb.String()
+}
+
+// requestScheme of client or server side request
+func requestScheme(r *http.Request) string {
+	switch {
+	case r.URL.Scheme != "":
+		return strings.ToLower(r.URL.Scheme)
+	case r.TLS != nil:
+		return "https"
+	}
+	return "http"
+}
+
+func headerComponent(h http.Header, name string) (string, error) {
+	values, ok := h[http.CanonicalHeaderKey(name)]
+	if !ok {
+		return "", errors.Errorf("missing header %q", name)
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+func parseSignatureParams(s string) ([]string, map[string]string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, nil, errors.Errorf("invalid signature params: %s", s)
+	}
+	end := strings.Index(s, ")")
+	if end < 0 {
+		return nil, nil, errors.Errorf("invalid signature params: %s", s)
+	}
+	var components []string
+	for _, v := range splitSFList(s[1:end], ' ') {
+		name, params, _ := cutString(v, ";")
+		name, err := strconv.Unquote(name)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid component %s", v)
+		}
+		if params != "" {
+			name += ";" + params
+		}
+		components = append(components, name)
+	}
+	return components, parseSFParams(s[end+1:]), nil
+}
+
+// parseSFDictionary parse structured field dictionary to raw member values
+func parseSFDictionary(s string) map[string]string {
+	m := map[string]string{}
+	for _, v := range splitSFList(s, ',') {
+		k, val, _ := cutString(v, "=")
+		m[strings.TrimSpace(k)] = strings.TrimSpace(val)
+	}
+	return m
+}
+
+func parseSFParams(s string) map[string]string {
+	m := map[string]string{}
+	for _, v := range splitSFList(s, ';') {
+		k, val, _ := cutString(v, "=")
+		if uq, err := strconv.Unquote(val); err == nil {
+			val = uq
+		}
+		m[strings.TrimSpace(k)] = val
+	}
+	return m
+}
+
+// splitSFList split by sep outside of quoted string and inner list
+func splitSFList(s string, sep rune) []string {
+	var out []string
+	quoted, depth, start := false, 0, 0
+	for i, c := range s {
+		switch {
+		case c == '"' && (i == 0 || s[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			if v := strings.TrimSpace(s[start:i]); v != "" {
+				out = append(out, v)
+			}
+			start = i + 1
+		}
+	}
+	if v := strings.TrimSpace(s[start:]); v != "" {
+		out = append(out, v)
+	}
+	return out
+}
+
+func decodeSFBytes(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != ':' || s[len(s)-1] != ':' {
+		return nil, errors.Errorf("invalid byte sequence: %q", s)
+	}
+	return base64.StdEncoding.DecodeString(s[1 : len(s)-1])
+}
+
+type httpSigAlgorithm struct {
+	alg    string
+	sign   func(data []byte) ([]byte, error)
+	verify func(data []byte, sig []byte) error
+}
+
+func (a httpSigAlgorithm) Algorithm() string {
+	return a.alg
+}
+
+func (a httpSigAlgorithm) Sign(data []byte) ([]byte, error) {
+	return a.sign(data)
+}
+
+func (a httpSigAlgorithm) Verify(data []byte, sig []byte) error {
+	return a.verify(data, sig)
+}
+
+// Ed25519Signer sign with ed25519
+func Ed25519Signer(key ed25519.PrivateKey) HTTPSigner {
+	return httpSigAlgorithm{alg: "ed25519", sign: func(data []byte) ([]byte, error) {
+		return ed25519.Sign(key, data), nil
+	}}
+}
+
+// Ed25519Verifier verify ed25519
+func Ed25519Verifier(key ed25519.PublicKey) HTTPVerifier {
+	return httpSigAlgorithm{alg: "ed25519", verify: func(data []byte, sig []byte) error {
+		if !ed25519.Verify(key, data, sig) {
+			return errors.New("invalid ed25519 signature")
+		}
+		return nil
+	}}
+}
+
+// ECDSAP256Signer sign with ecdsa-p256-sha256
+func ECDSAP256Signer(key *ecdsa.PrivateKey) HTTPSigner {
+	return httpSigAlgorithm{alg: "ecdsa-p256-sha256", sign: func(data []byte) ([]byte, error) {
+		if key.Curve != elliptic.P256() {
+			return nil, errors.New("ecdsa-p256-sha256 require P-256 key")
+		}
+		sum := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+		if err != nil {
+			return nil, err
+		}
+		sig := make([]byte, 64) //nolint:gomnd
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	}}
+}
+
+// ECDSAP256Verifier verify ecdsa-p256-sha256
+func ECDSAP256Verifier(key *ecdsa.PublicKey) HTTPVerifier {
+	return httpSigAlgorithm{alg: "ecdsa-p256-sha256", verify: func(data []byte, sig []byte) error {
+		if len(sig) != 64 { //nolint:gomnd
+			return errors.New("invalid ecdsa-p256-sha256 signature length")
+		}
+		sum := sha256.Sum256(data)
+		if !ecdsa.Verify(key, sum[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])) {
+			return errors.New("invalid ecdsa-p256-sha256 signature")
+		}
+		return nil
+	}}
+}
+
+// HMACSHA256Signer sign with hmac-sha256
+func HMACSHA256Signer(key []byte) HTTPSigner {
+	return httpSigAlgorithm{alg: "hmac-sha256", sign: func(data []byte) ([]byte, error) {
+		return hmacSum(sha256.New, key, data), nil
+	}}
+}
+
+// HMACSHA256Verifier verify hmac-sha256
+func HMACSHA256Verifier(key []byte) HTTPVerifier {
+	return httpSigAlgorithm{alg: "hmac-sha256", verify: func(data []byte, sig []byte) error {
+		if !hmac.Equal(hmacSum(sha256.New, key, data), sig) {
+			return errors.New("invalid hmac-sha256 signature")
+		}
+		return nil
+	}}
+}
+
+var rsaPSSOptions = &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512} //nolint:gomnd
+
+// RSAPSSSigner sign with rsa-pss-sha512
+func RSAPSSSigner(key *rsa.PrivateKey) HTTPSigner {
+	return httpSigAlgorithm{alg: "rsa-pss-sha512", sign: func(data []byte) ([]byte, error) {
+		sum := sha512.Sum512(data)
+		return rsa.SignPSS(rand.Reader, key, crypto.SHA512, sum[:], rsaPSSOptions)
+	}}
+}
+
+// RSAPSSVerifier verify rsa-pss-sha512
+func RSAPSSVerifier(key *rsa.PublicKey) HTTPVerifier {
+	return httpSigAlgorithm{alg: "rsa-pss-sha512", verify: func(data []byte, sig []byte) error {
+		sum := sha512.Sum512(data)
+		return rsa.VerifyPSS(key, crypto.SHA512, sum[:], sig, rsaPSSOptions)
+	}}
+}
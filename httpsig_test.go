@@ -0,0 +1,140 @@
+package req_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestHTTPSignature(t *testing.T) {
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	hmacKey := []byte("secret")
+	for _, tc := range []struct {
+		signer   req.HTTPSigner
+		verifier req.HTTPVerifier
+	}{
+		{req.Ed25519Signer(edKey), req.Ed25519Verifier(edPub)},
+		{req.ECDSAP256Signer(ecKey), req.ECDSAP256Verifier(&ecKey.PublicKey)},
+		{req.HMACSHA256Signer(hmacKey), req.HMACSHA256Verifier(hmacKey)},
+		{req.RSAPSSSigner(rsaKey), req.RSAPSSVerifier(&rsaKey.PublicKey)},
+	} {
+		resolve := func(keyID string, alg string) (req.HTTPVerifier, error) {
+			assert.Equal(t, "test-key", keyID)
+			return tc.verifier, nil
+		}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, `{"hello":"world"}`, string(body))
+			assert.Equal(t, "sha-256=:k6I5cakU5erL8KjSUVTNownDwccvu5kU1Hxg88toFYg=:", r.Header.Get("Content-Digest"))
+			assert.Contains(t, r.Header.Get("Signature-Input"), `sig1=("@method" "@target-uri" "content-digest" "content-type");created=`)
+			if err := req.VerifyRequestSignature(r, resolve); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			// sign response
+			res, _ := req.Request{
+				Method:  http.MethodPost,
+				BaseURL: "http://localhost",
+				RawBody: []byte("OK"),
+				Options: []interface{}{
+					req.ContentDigestHook(),
+					req.HTTPSignatureHook(&req.HTTPSignatureOptions{KeyID: "test-key", Signer: tc.signer, Components: []string{"content-digest"}}),
+				},
+			}.NewRequest()
+			for _, v := range []string{"Content-Digest", "Signature-Input", "Signature"} {
+				w.Header().Set(v, res.Header.Get(v))
+			}
+			_, _ = w.Write([]byte("OK"))
+		}))
+
+		client := req.Request{
+			Method:  http.MethodPost,
+			BaseURL: server.URL,
+			URL:     "/pay",
+			Body:    map[string]string{"hello": "world"},
+			Options: []interface{}{
+				req.JSONEncode,
+				req.ContentDigestHook(),
+				req.HTTPSignatureHook(&req.HTTPSignatureOptions{KeyID: "test-key", Signer: tc.signer}),
+			},
+		}
+		out, res, err := client.WithHook(req.HTTPSignatureVerifyHook(resolve)).FetchString()
+		assert.NoError(t, err, tc.signer.Algorithm())
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "OK", out)
+
+		// tampered
+		r, err := client.NewRequest()
+		assert.NoError(t, err)
+		r.Header.Set("Content-Type", "text/plain")
+		assert.True(t, errors.Is(req.VerifyRequestSignature(r, resolve), req.ErrSignatureMismatch))
+		server.Close()
+	}
+}
+
+func TestECDSAP256SignerCurve(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, err := req.ECDSAP256Signer(key).Sign([]byte("data"))
+	assert.Error(t, err)
+}
+
+func TestVerifyContentDigest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Digest", "sha-256=:k6I5cakU5erL8KjSUVTNownDwccvu5kU1Hxg88toFYg=:")
+		_, _ = w.Write([]byte(r.URL.Query().Get("body")))
+	}))
+	defer server.Close()
+
+	res, err := req.Request{BaseURL: server.URL, Query: map[string]string{"body": `{"hello":"world"}`}}.Do()
+	assert.NoError(t, err)
+	assert.NoError(t, req.VerifyContentDigest(res))
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, `{"hello":"world"}`, string(body))
+
+	res, err = req.Request{BaseURL: server.URL, Query: map[string]string{"body": "tampered"}}.Do()
+	assert.NoError(t, err)
+	assert.True(t, errors.Is(req.VerifyContentDigest(res), req.ErrSignatureMismatch))
+}
+
+type captureSigner struct{ base string }
+
+func (s *captureSigner) Algorithm() string { return "test" }
+
+func (s *captureSigner) Sign(data []byte) ([]byte, error) {
+	s.base = string(data)
+	return data, nil
+}
+
+func TestHTTPSignatureQueryParam(t *testing.T) {
+	// RFC 9421 section 2.2.8
+	signer := &captureSigner{}
+	_, err := req.Request{
+		URL: "https://example.com/parameters?var=this%20is%20a%20big%0Avalue&bar=with+plus+whitespace&fa%C3%A7ade%22%3A%20=something",
+		Options: []interface{}{req.HTTPSignatureHook(&req.HTTPSignatureOptions{
+			Signer: signer,
+			Components: []string{
+				`@query-param;name="var"`,
+				`@query-param;name="bar"`,
+				`@query-param;name="fa%C3%A7ade%22%3A%20"`,
+			},
+		})},
+	}.NewRequest()
+	assert.NoError(t, err)
+	assert.Contains(t, signer.base, `"@query-param";name="var": this%20is%20a%20big%0Avalue
+"@query-param";name="bar": with%20plus%20whitespace
+"@query-param";name="fa%C3%A7ade%22%3A%20": something
+`)
+}
@@ -0,0 +1,144 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// MultipartFile file part of multipart form
+type MultipartFile struct {
+	Filename    string // Filename default base name of Path
+	ContentType string // ContentType default application/octet-stream
+	Content     []byte // Content of file, read from Path when nil
+	Path        string // Path of file to read
+}
+
+// MultipartField ordered field of multipart form, File take precedence over Value
+type MultipartField struct {
+	Name  string
+	Value string
+	File  *MultipartFile
+}
+
+// MultipartFormEncode encode body as multipart/form-data, Content-Type with boundary is the result of encoder,
+// boundary of a preset multipart Content-Type is kept.
+// Body can be []MultipartField or map of string, []byte, MultipartFile and *MultipartFile,
+// other body is converted by ValuesOf.
+var MultipartFormEncode = Hook{
+	Name: "MultipartFormEncode",
+	EncodeContent: func(ctx context.Context, body interface{}) ([]byte, string, error) {
+		fields, err := multipartFields(body)
+		if err != nil {
+			return nil, "", err
+		}
+		buf := &bytes.Buffer{}
+		w := multipart.NewWriter(buf)
+		if re := FromContext(ctx); re != nil {
+			if _, params, err := mime.ParseMediaType(re.Header.Get("Content-Type")); err == nil && params["boundary"] != "" {
+				if err = w.SetBoundary(params["boundary"]); err != nil {
+					return nil, "", err
+				}
+			}
+		}
+		for _, f := range fields {
+			if f.File == nil {
+				if err = w.WriteField(f.Name, f.Value); err != nil {
+					return nil, "", err
+				}
+				continue
+			}
+			if err = writeMultipartFile(w, f.Name, f.File); err != nil {
+				return nil, "", err
+			}
+		}
+		if err = w.Close(); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), w.FormDataContentType(), nil
+	},
+}
+
+func writeMultipartFile(w *multipart.Writer, name string, f *MultipartFile) error {
+	content := f.Content
+	if content == nil && f.Path != "" {
+		b, err := os.ReadFile(f.Path)
+		if err != nil {
+			return errors.Wrap(err, "read multipart file")
+		}
+		content = b
+	}
+	filename := f.Filename
+	if filename == "" && f.Path != "" {
+		filename = filepath.Base(f.Path)
+	}
+	typ := f.ContentType
+	if typ == "" {
+		typ = "application/octet-stream"
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", multipartDisposition(name, filename))
+	h.Set("Content-Type", typ)
+	pw, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = pw.Write(content)
+	return err
+}
+
+func multipartFields(body interface{}) ([]MultipartField, error) {
+	switch v := body.(type) {
+	case []MultipartField:
+		return v, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var fields []MultipartField
+		for _, k := range keys {
+			switch fv := v[k].(type) {
+			case MultipartFile:
+				fields = append(fields, MultipartField{Name: k, File: &fv})
+			case *MultipartFile:
+				fields = append(fields, MultipartField{Name: k, File: fv})
+			case []byte:
+				fields = append(fields, MultipartField{Name: k, Value: string(fv)})
+			default:
+				values, err := ValuesOf(map[string]interface{}{k: fv})
+				if err != nil {
+					return nil, err
+				}
+				for _, s := range values[k] {
+					fields = append(fields, MultipartField{Name: k, Value: s})
+				}
+			}
+		}
+		return fields, nil
+	}
+	values, err := ValuesOf(body)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var fields []MultipartField
+	for _, k := range keys {
+		for _, s := range values[k] {
+			fields = append(fields, MultipartField{Name: k, Value: s})
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,105 @@
+package req_test
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestMultipartFormEncode(t *testing.T) {
+	type part struct {
+		Filename    string
+		ContentType string
+		Content     string
+	}
+	var parts map[string]part
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts = map[string]part{}
+		typ, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		assert.NoError(t, err)
+		assert.Equal(t, "multipart/form-data", typ)
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			b, _ := io.ReadAll(p)
+			parts[p.FormName()] = part{p.FileName(), p.Header.Get("Content-Type"), string(b)}
+		}
+	}))
+	defer s.Close()
+
+	file := filepath.Join(t.TempDir(), "a.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("from file"), 0o600))
+
+	client := req.Request{BaseURL: s.URL, Method: http.MethodPost, Options: []interface{}{req.MultipartFormEncode}}
+	_, err := client.With(req.Request{Body: map[string]interface{}{
+		"name": "wener",
+		"age":  18,
+		"raw":  []byte("bytes"),
+		"doc":  req.MultipartFile{Filename: "doc.json", ContentType: "application/json", Content: []byte(`{}`)},
+		"file": &req.MultipartFile{Path: file},
+	}}).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]part{
+		"name": {"", "", "wener"},
+		"age":  {"", "", "18"},
+		"raw":  {"", "", "bytes"},
+		"doc":  {"doc.json", "application/json", `{}`},
+		"file": {"a.txt", "application/octet-stream", "from file"},
+	}, parts)
+
+	_, err = client.With(req.Request{Body: []req.MultipartField{
+		{Name: "a", Value: "1"},
+		{Name: "b", File: &req.MultipartFile{Filename: "b.bin", Content: []byte{0, 1}}},
+	}}).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]part{
+		"a": {"", "", "1"},
+		"b": {"b.bin", "application/octet-stream", "\x00\x01"},
+	}, parts)
+
+	// empty form
+	parts = nil
+	_, err = client.With(req.Request{Body: map[string]interface{}{}}).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]part{}, parts)
+
+	// preset boundary
+	r, err := client.With(req.Request{
+		Header: http.Header{"Content-Type": []string{"multipart/form-data; boundary=preset"}},
+		Body:   []req.MultipartField{{Name: "a", Value: "1"}},
+	}).NewRequest()
+	assert.NoError(t, err)
+	b, _ := io.ReadAll(r.Body)
+	assert.True(t, strings.HasPrefix(string(b), "--preset\r\n"), string(b))
+
+	// nil body is not encoded
+	r, err = client.NewRequest()
+	assert.NoError(t, err)
+	assert.Nil(t, r.Body)
+	assert.Empty(t, r.Header.Get("Content-Type"))
+
+	// header of origin Request is not changed
+	shared := client.With(req.Request{Header: http.Header{"X-A": []string{"1"}}, Body: map[string]interface{}{"a": 1}})
+	r, err = shared.NewRequest()
+	assert.NoError(t, err)
+	assert.Contains(t, r.Header.Get("Content-Type"), "multipart/form-data; boundary=")
+	assert.Empty(t, shared.Header.Get("Content-Type"))
+
+	_, err = client.With(req.Request{Body: map[string]interface{}{
+		"file": &req.MultipartFile{Path: filepath.Join(t.TempDir(), "missing")},
+	}}).Do()
+	assert.Error(t, err)
+}
@@ -0,0 +1,186 @@
+package req
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OAuth1 signature methods
+const (
+	OAuth1HMACSHA1  = "HMAC-SHA1"
+	OAuth1RSASHA1   = "RSA-SHA1"
+	OAuth1PlainText = "PLAINTEXT"
+)
+
+// OAuth1Options options for OAuth1Hook
+type OAuth1Options struct {
+	ConsumerKey     string
+	ConsumerSecret  string
+	Token           string
+	TokenSecret     string
+	SignatureMethod string          // SignatureMethod default HMAC-SHA1
+	PrivateKey      *rsa.PrivateKey // PrivateKey for RSA-SHA1
+	Realm           string
+	Callback        string           // Callback add oauth_callback for request token
+	Verifier        string           // Verifier add oauth_verifier for access token
+	Now             func() time.Time // Now for oauth_timestamp, default time.Now
+	Nonce           func() string    // Nonce for oauth_nonce, default random hex
+}
+
+// OAuth1Hook sign request with OAuth 1.0a, add Authorization header
+//
+// query params and form-encoded body params are included in signature base string.
+func OAuth1Hook(o *OAuth1Options) Hook {
+	return Hook{
+		Name:  "OAuth1",
+		Order: -10,
+		OnRequest: func(r *http.Request) error {
+			if o == nil {
+				return errors.New("OAuth1Hook: missing options")
+			}
+			return o.sign(r)
+		},
+	}
+}
+
+func (o *OAuth1Options) sign(r *http.Request) error {
+	method := o.SignatureMethod
+	if method == "" {
+		method = OAuth1HMACSHA1
+	}
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+	nonce := newNonce
+	if o.Nonce != nil {
+		nonce = o.Nonce
+	}
+
+	oauth := map[string]string{
+		"oauth_consumer_key":     o.ConsumerKey,
+		"oauth_nonce":            nonce(),
+		"oauth_signature_method": method,
+		"oauth_timestamp":        strconv.FormatInt(now().Unix(), 10),
+		"oauth_version":          "1.0",
+	}
+	for k, v := range map[string]string{
+		"oauth_token":    o.Token,
+		"oauth_callback": o.Callback,
+		"oauth_verifier": o.Verifier,
+	} {
+		if v != "" {
+			oauth[k] = v
+		}
+	}
+
+	body, err := requestBody(r)
+	if err != nil {
+		return err
+	}
+	params, err := requestParams(r, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range oauth {
+		params.Set(k, v)
+	}
+
+	key := oauth1Escape(o.ConsumerSecret) + "&" + oauth1Escape(o.TokenSecret)
+	var sig string
+	switch method {
+	case OAuth1HMACSHA1:
+		sig = base64.StdEncoding.EncodeToString(hmacSum(sha1.New, []byte(key), []byte(oauth1BaseString(r, params))))
+	case OAuth1RSASHA1:
+		if o.PrivateKey == nil {
+			return errors.New("OAuth1Hook: RSA-SHA1 need PrivateKey")
+		}
+		sum := sha1.Sum([]byte(oauth1BaseString(r, params))) //nolint:gosec
+		b, err := rsa.SignPKCS1v15(rand.Reader, o.PrivateKey, crypto.SHA1, sum[:])
+		if err != nil {
+			return err
+		}
+		sig = base64.StdEncoding.EncodeToString(b)
+	case OAuth1PlainText:
+		sig = key
+	default:
+		return errors.Errorf("OAuth1Hook: unsupported signature method %q", method)
+	}
+	oauth["oauth_signature"] = sig
+
+	keys := make([]string, 0, len(oauth))
+	for k := range oauth {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	if o.Realm != "" {
+		pairs = append(pairs, `realm="`+oauth1Escape(o.Realm)+`"`)
+	}
+	for _, k := range keys {
+		pairs = append(pairs, k+`="`+oauth1Escape(oauth[k])+`"`)
+	}
+	r.Header.Set("Authorization", "OAuth "+strings.Join(pairs, ", "))
+	return nil
+}
+
+// oauth1BaseString build signature base string of RFC 5849 3.4.1
+func oauth1BaseString(r *http.Request, params url.Values) string {
+	type pair struct{ k, v string }
+	var pairs []pair
+	for k, values := range params {
+		for _, v := range values {
+			pairs = append(pairs, pair{oauth1Escape(k), oauth1Escape(v)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].k == pairs[j].k {
+			return pairs[i].v < pairs[j].v
+		}
+		return pairs[i].k < pairs[j].k
+	})
+	normalized := make([]string, len(pairs))
+	for i, v := range pairs {
+		normalized[i] = v.k + "=" + v.v
+	}
+
+	// base string uri keep the escaped path, empty path is "/"
+	scheme, host := strings.ToLower(r.URL.Scheme), strings.ToLower(requestHeader(r, "Host"))
+	if h, p, err := net.SplitHostPort(host); err == nil && ((p == "80" && scheme == "http") || (p == "443" && scheme == "https")) {
+		host = h
+		if strings.Contains(h, ":") {
+			host = "[" + h + "]"
+		}
+	}
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	uri := scheme + "://" + host + path
+	return strings.ToUpper(r.Method) + "&" + oauth1Escape(uri) + "&" + oauth1Escape(strings.Join(normalized, "&"))
+}
+
+// oauth1Escape percent encode by RFC 3986, only unreserved characters are kept
+func oauth1Escape(s string) string {
+	sb := strings.Builder{}
+	for _, c := range []byte(s) {
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '.' || c == '_' || c == '~' {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteString("%" + strings.ToUpper(strconv.FormatInt(int64(c)|0x100, 16)[1:]))
+	}
+	return sb.String()
+}
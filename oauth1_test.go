@@ -0,0 +1,125 @@
+package req_test
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestOAuth1Hook(t *testing.T) {
+	// https://developer.twitter.com/en/docs/authentication/oauth-1-0a/creating-a-signature
+	r, err := req.Request{
+		Method:  http.MethodPost,
+		BaseURL: "https://api.twitter.com",
+		URL:     "/1.1/statuses/update.json",
+		Query:   map[string]string{"include_entities": "true"},
+		Body:    map[string]string{"status": "Hello Ladies + Gentlemen, a signed OAuth request!"},
+		Options: []interface{}{
+			req.FormEncode,
+			req.OAuth1Hook(&req.OAuth1Options{
+				ConsumerKey:    "xvz1evFS4wEEPTGEFPHBog",
+				ConsumerSecret: "kAcSOqF21Fu85e7zjz7ZN2U4ZRhfV3WpwPAoE3Z7kBw",
+				Token:          "370773112-GmHxMAgYyLbNEtIKZeRNFsMKPR9EyMZeS9weJAEb",
+				TokenSecret:    "LswwdoUaIvS8ltyTt5jkRh4J50vUPVVHtR2YPi5kE",
+				Now:            func() time.Time { return time.Unix(1318622958, 0) },
+				Nonce:          func() string { return "kYjzVBB8Y0ZFabxSWbWovY3uYSQ2pTgmZeNu2VS4cg" },
+			}),
+		},
+	}.NewRequest()
+	assert.NoError(t, err)
+	assert.Equal(t, `OAuth oauth_consumer_key="xvz1evFS4wEEPTGEFPHBog", `+
+		`oauth_nonce="kYjzVBB8Y0ZFabxSWbWovY3uYSQ2pTgmZeNu2VS4cg", `+
+		`oauth_signature="hCtSmYh%2BiHYCEqBWrE7C7hYmtUk%3D", `+
+		`oauth_signature_method="HMAC-SHA1", `+
+		`oauth_timestamp="1318622958", `+
+		`oauth_token="370773112-GmHxMAgYyLbNEtIKZeRNFsMKPR9EyMZeS9weJAEb", `+
+		`oauth_version="1.0"`, r.Header.Get("Authorization"))
+}
+
+func TestOAuth1HookMethods(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	{
+		r, err := req.Request{
+			BaseURL: "https://magento.local",
+			URL:     "/rest/V1/products",
+			Options: []interface{}{req.OAuth1Hook(&req.OAuth1Options{
+				ConsumerKey:     "key",
+				SignatureMethod: req.OAuth1RSASHA1,
+				PrivateKey:      key,
+				Realm:           "Magento",
+			})},
+		}.NewRequest()
+		assert.NoError(t, err)
+		assert.Regexp(t, `^OAuth realm="Magento", oauth_consumer_key="key", .*oauth_signature_method="RSA-SHA1"`, r.Header.Get("Authorization"))
+	}
+	{
+		r, err := req.Request{
+			BaseURL: "https://magento.local",
+			Options: []interface{}{req.OAuth1Hook(&req.OAuth1Options{
+				ConsumerKey:     "key",
+				ConsumerSecret:  "a b",
+				TokenSecret:     "c",
+				SignatureMethod: req.OAuth1PlainText,
+			})},
+		}.NewRequest()
+		assert.NoError(t, err)
+		assert.Contains(t, r.Header.Get("Authorization"), `oauth_signature="a%2520b%26c"`)
+	}
+	{
+		_, err := req.Request{
+			BaseURL: "https://magento.local",
+			Options: []interface{}{req.OAuth1Hook(&req.OAuth1Options{SignatureMethod: req.OAuth1RSASHA1})},
+		}.NewRequest()
+		assert.Error(t, err)
+	}
+}
+
+func TestOAuth1HookBaseURI(t *testing.T) {
+	// expected signature of base string from Authorization params, base string uri per RFC 5849 section 3.4.1.2
+	verify := func(r *http.Request, uri string) {
+		var params []string
+		var sig string
+		for _, m := range regexp.MustCompile(`(\w+)="([^"]*)"`).FindAllStringSubmatch(r.Header.Get("Authorization"), -1) {
+			if m[1] == "oauth_signature" {
+				sig, _ = url.QueryUnescape(m[2])
+				continue
+			}
+			params = append(params, m[1]+"="+m[2])
+		}
+		sort.Strings(params)
+		base := "GET&" + url.QueryEscape(uri) + "&" + url.QueryEscape(strings.Join(params, "&"))
+		mac := hmac.New(sha1.New, []byte("cs&"))
+		mac.Write([]byte(base))
+		assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), sig, base)
+	}
+	for u, uri := range map[string]string{
+		"https://Example.COM":       "https://example.com/",
+		"https://example.com:443":   "https://example.com/",
+		"http://example.com:8080/":  "http://example.com:8080/",
+		"https://example.com/a%2Fb": "https://example.com/a%2Fb",
+	} {
+		r, err := req.Request{
+			BaseURL: u,
+			Options: []interface{}{req.OAuth1Hook(&req.OAuth1Options{
+				ConsumerKey:    "ck",
+				ConsumerSecret: "cs",
+				Nonce:          func() string { return "nonce" },
+			})},
+		}.NewRequest()
+		assert.NoError(t, err)
+		verify(r, uri)
+	}
+}
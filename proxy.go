@@ -0,0 +1,146 @@
+package req
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ProxyOptions options for ProxyHook
+type ProxyOptions struct {
+	URL         string                                  // URL of proxy, support http, https and socks5, user info is used as proxy auth
+	Select      func(r *http.Request) (*url.URL, error) // Select proxy per request, return nil for direct connection, override URL
+	NoProxy     string                                  // NoProxy comma separated hosts connect directly, same as NO_PROXY env
+	Environment bool                                    // Environment use HTTP_PROXY, HTTPS_PROXY and NO_PROXY env when URL and Select is empty
+}
+
+// ProxyHook connect through proxy, per client instead of the environment of http.DefaultTransport
+func ProxyHook(o *ProxyOptions) Hook {
+	h := transportHook("Proxy", 100, func(t *http.Transport) error {
+		if o == nil {
+			t.Proxy = nil
+			return nil
+		}
+		proxy, err := o.proxy()
+		if err != nil {
+			return err
+		}
+		t.Proxy = proxy
+		return nil
+	})
+	h.curl = func(r *http.Request) (args, comments []string) {
+		if o == nil {
+			return []string{"--noproxy " + shellQuote("*")}, nil
+		}
+		proxy, err := o.proxy()
+		if err != nil {
+			return nil, nil
+		}
+		u, err := proxy(r)
+		switch {
+		case err != nil:
+			return nil, []string{"# unsupported: proxy " + err.Error()}
+		case u == nil:
+			return []string{"--noproxy " + shellQuote("*")}, nil
+		}
+		return []string{"-x " + shellQuote(u.String())}, nil
+	}
+	return h
+}
+
+// proxy select func of options for http.Transport
+func (o ProxyOptions) proxy() (func(r *http.Request) (*url.URL, error), error) {
+	var fixed *url.URL
+	if o.URL != "" {
+		u, err := url.Parse(o.URL)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse proxy url")
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, errors.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		fixed = u
+	}
+	return func(r *http.Request) (*url.URL, error) {
+		if o.NoProxy != "" && MatchNoProxy(o.NoProxy, canonicalHostPort(r.URL)) {
+			return nil, nil
+		}
+		switch {
+		case o.Select != nil:
+			return o.Select(r)
+		case fixed != nil:
+			return fixed, nil
+		case o.Environment:
+			return http.ProxyFromEnvironment(r)
+		}
+		return nil, nil
+	}, nil
+}
+
+// MatchNoProxy check if host:port matches NO_PROXY like rules
+//
+// Rules are comma or space separated, support "*", ip, cidr, domain match itself and sub domains,
+// ".domain" or "*.domain" match sub domains only, rule with port only match that port.
+func MatchNoProxy(noProxy string, hostport string) bool {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	ip := net.ParseIP(host)
+	for _, rule := range strings.FieldsFunc(noProxy, func(r rune) bool { return r == ',' || r == ' ' }) {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(rule); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(rule); err == nil {
+			if p != port {
+				continue
+			}
+			rule = h
+		}
+		if ruleIP := net.ParseIP(rule); ruleIP != nil {
+			if ip != nil && ruleIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		rule = strings.TrimSuffix(rule, ".")
+		switch {
+		case strings.HasPrefix(rule, "*."):
+			if strings.HasSuffix(host, rule[1:]) {
+				return true
+			}
+		case strings.HasPrefix(rule, "."):
+			if strings.HasSuffix(host, rule) {
+				return true
+			}
+		case host == rule || strings.HasSuffix(host, "."+rule):
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalHostPort return host:port of url with default port of scheme
+func canonicalHostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,150 @@
+package req_test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestProxyHook(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("direct"))
+	}))
+	defer target.Close()
+	tlsTarget := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tunnel"))
+	}))
+	defer tlsTarget.Close()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			upstream, err := net.Dial("tcp", r.Host)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			go func() { _, _ = io.Copy(upstream, conn) }()
+			_, _ = io.Copy(conn, upstream)
+			_ = conn.Close()
+			return
+		}
+		_, _ = w.Write([]byte("proxy " + r.URL.String() + " " + r.Header.Get("Proxy-Authorization")))
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	proxyURL.User = url.UserPassword("user", "pass")
+
+	fetch := func(u string, o *req.ProxyOptions) string {
+		out, _, err := req.Request{URL: u}.WithHook(req.ProxyHook(o), req.TLSHook(&req.TLSOptions{InsecureSkipVerify: true})).FetchString()
+		assert.NoError(t, err)
+		return out
+	}
+	assert.Equal(t, "proxy http://example.internal/a Basic dXNlcjpwYXNz", fetch("http://example.internal/a", &req.ProxyOptions{URL: proxyURL.String()}))
+	assert.Equal(t, "direct", fetch(target.URL, &req.ProxyOptions{URL: proxy.URL, NoProxy: "example.internal,127.0.0.0/8"}))
+	assert.Equal(t, "tunnel", fetch(tlsTarget.URL, &req.ProxyOptions{URL: proxy.URL}))
+	assert.Equal(t, "direct", fetch(target.URL, &req.ProxyOptions{Select: func(r *http.Request) (*url.URL, error) {
+		if r.URL.Hostname() == "example.internal" {
+			return proxyURL, nil
+		}
+		return nil, nil
+	}}))
+
+	_, _, err := req.Request{URL: target.URL}.WithHook(req.ProxyHook(&req.ProxyOptions{URL: "ftp://proxy"})).FetchString()
+	assert.Error(t, err)
+}
+
+func TestProxyHookSocks5(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("socks"))
+	}))
+	defer target.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer l.Close()
+	auth := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serveSocks5(conn, auth)
+			}()
+		}
+	}()
+
+	out, _, err := req.Request{URL: target.URL}.WithHook(req.ProxyHook(&req.ProxyOptions{URL: "socks5://user:pass@" + l.Addr().String()})).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "socks", out)
+	assert.Equal(t, "user:pass", <-auth)
+}
+
+// serveSocks5 minimal RFC 1928 CONNECT with username/password auth
+func serveSocks5(conn net.Conn, auth chan<- string) {
+	buf := make([]byte, 512)
+	read := func(n int) []byte {
+		_, _ = io.ReadFull(conn, buf[:n])
+		return buf[:n]
+	}
+	methods := int(read(2)[1])
+	read(methods)
+	_, _ = conn.Write([]byte{5, 2})
+	user := string(read(int(read(2)[1])))
+	pass := string(read(int(read(1)[0])))
+	_, _ = conn.Write([]byte{1, 0})
+	auth <- user + ":" + pass
+
+	head := read(4)
+	var host string
+	switch head[3] {
+	case 1:
+		host = net.IP(read(4)).String()
+	case 3:
+		host = string(read(int(read(1)[0])))
+	}
+	port := binary.BigEndian.Uint16(read(2))
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+	_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+	go func() { _, _ = io.Copy(upstream, conn) }()
+	_, _ = io.Copy(conn, upstream)
+}
+
+func TestMatchNoProxy(t *testing.T) {
+	for _, tc := range []struct {
+		rules string
+		host  string
+		match bool
+	}{
+		{"*", "wener.me:443", true},
+		{"wener.me", "wener.me:443", true},
+		{"wener.me", "api.wener.me:443", true},
+		{"wener.me", "notwener.me:443", false},
+		{".wener.me", "wener.me:443", false},
+		{"*.wener.me", "api.wener.me:80", true},
+		{"wener.me:8080", "wener.me:80", false},
+		{"wener.me:8080", "wener.me:8080", true},
+		{"10.0.0.0/8, 192.168.1.1", "10.1.2.3:80", true},
+		{"10.0.0.0/8 192.168.1.1", "192.168.1.1:80", true},
+		{"10.0.0.0/8,192.168.1.1", "192.168.1.2:80", false},
+		{"", "wener.me:80", false},
+	} {
+		assert.Equal(t, tc.match, req.MatchNoProxy(tc.rules, tc.host), "%s %s", tc.rules, tc.host)
+	}
+}
@@ -0,0 +1,154 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimitOptions options for RateLimitHook
+type RateLimitOptions struct {
+	Rate     float64                      // Rate requests per second, <= 0 only limited by server headers
+	Burst    int                          // Burst size of token bucket, default 1
+	Key      func(r *http.Request) string // Key of bucket, default one bucket for all requests, HostKey for per host bucket
+	Adaptive bool                         // Adaptive pause bucket until reset when server report quota exhausted or Retry-After
+}
+
+// RateLimitHook token bucket rate limit, wait for token respecting the request Context
+//
+// Adaptive support X-RateLimit-Remaining/X-RateLimit-Reset, RateLimit-Remaining/RateLimit-Reset, RateLimit and Retry-After headers.
+func RateLimitHook(o *RateLimitOptions) Hook {
+	if o == nil {
+		o = &RateLimitOptions{}
+	}
+	var mu sync.Mutex
+	buckets := map[string]*tokenBucket{}
+	bucket := func(r *http.Request) *tokenBucket {
+		key := ""
+		if o.Key != nil {
+			key = o.Key(r)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		b, ok := buckets[key]
+		if !ok {
+			b = &tokenBucket{rate: o.Rate, burst: float64(o.Burst), tokens: float64(o.Burst), last: time.Now()}
+			if b.burst < 1 {
+				b.burst, b.tokens = 1, 1
+			}
+			buckets[key] = b
+		}
+		return b
+	}
+	return Hook{
+		Name:  "RateLimit",
+		Order: -40,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				b := bucket(r)
+				if err := b.wait(r.Context()); err != nil {
+					return nil, err
+				}
+				res, err := next.RoundTrip(r)
+				if err == nil && o.Adaptive {
+					if until, ok := rateLimitReset(res, time.Now()); ok {
+						b.pause(until)
+					}
+				}
+				return res, err
+			})
+		},
+	}
+}
+
+type tokenBucket struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		var delay time.Duration
+		switch {
+		case now.Before(b.pausedUntil):
+			delay = b.pausedUntil.Sub(now)
+		case b.rate <= 0:
+			b.mu.Unlock()
+			return nil
+		default:
+			b.tokens += now.Sub(b.last).Seconds() * b.rate
+			if b.tokens > b.burst {
+				b.tokens = b.burst
+			}
+			b.last = now
+			if b.tokens >= 1 {
+				b.tokens--
+				b.mu.Unlock()
+				return nil
+			}
+			delay = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (b *tokenBucket) pause(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// rateLimitReset return time to resume when server report quota exhausted
+func rateLimitReset(res *http.Response, now time.Time) (time.Time, bool) {
+	h := res.Header
+	if d, ok := retryAfter(h.Get("Retry-After"), now); ok {
+		return now.Add(d), true
+	}
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		if s, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			// GitHub style epoch seconds or delta seconds
+			if s > 1e9 {
+				return time.Unix(s, 0), true
+			}
+			return now.Add(time.Duration(s) * time.Second), true
+		}
+	}
+	remaining, reset := h.Get("RateLimit-Remaining"), h.Get("RateLimit-Reset")
+	if v := h.Get("RateLimit"); v != "" {
+		// draft structured field - limit=10, remaining=0, reset=5
+		for _, p := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ';' }) {
+			k, val, _ := cutString(strings.TrimSpace(p), "=")
+			switch k {
+			case "remaining", "r":
+				remaining = val
+			case "reset", "t":
+				reset = val
+			}
+		}
+	}
+	if remaining == "0" {
+		if s, err := strconv.Atoi(reset); err == nil {
+			return now.Add(time.Duration(s) * time.Second), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,62 @@
+package req_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestRateLimitHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range r.URL.Query() {
+			w.Header().Set(k, v[0])
+		}
+	}))
+	defer server.Close()
+
+	{
+		client := req.Request{BaseURL: server.URL}.WithHook(req.RateLimitHook(&req.RateLimitOptions{Rate: 20, Burst: 2}))
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			_, err := client.Do()
+			assert.NoError(t, err)
+		}
+		// 2 burst + 2 * 50ms
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(90*time.Millisecond))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := client.With(req.Request{Context: ctx}).Do()
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	}
+
+	for _, q := range []map[string]string{
+		{"Retry-After": "10"},
+		{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "10"},
+		{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "4102444800"},
+		{"RateLimit-Remaining": "0", "RateLimit-Reset": "10"},
+		{"RateLimit": "limit=10, remaining=0, reset=10"},
+	} {
+		client := req.Request{BaseURL: server.URL}.WithHook(req.RateLimitHook(&req.RateLimitOptions{Adaptive: true, Key: req.HostKey}))
+		_, err := client.With(req.Request{Query: q}).Do()
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err = client.With(req.Request{Context: ctx}).Do()
+		cancel()
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v %v", q, err)
+	}
+	{
+		client := req.Request{BaseURL: server.URL}.WithHook(req.RateLimitHook(&req.RateLimitOptions{Adaptive: true}))
+		_, err := client.With(req.Request{Query: map[string]string{"X-RateLimit-Remaining": "1", "X-RateLimit-Reset": "10"}}).Do()
+		assert.NoError(t, err)
+		_, err = client.Do()
+		assert.NoError(t, err)
+	}
+}
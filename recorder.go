@@ -0,0 +1,338 @@
+package req
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInteractionNotFound returned by Recorder when no recorded interaction match the request
+var ErrInteractionNotFound = errors.New("req: interaction not found")
+
+// RecorderMode mode of Recorder
+type RecorderMode int
+
+// Recorder modes
+const (
+	RecorderAuto   RecorderMode = iota // RecorderAuto replay when cassette exists, record otherwise
+	RecorderRecord                     // RecorderRecord send request and record interactions, overwrite cassette
+	RecorderReplay                     // RecorderReplay serve from cassette only
+)
+
+// Cassette recorded interactions
+type Cassette struct {
+	Version      int                    `json:"version" yaml:"version"`
+	Interactions []*CassetteInteraction `json:"interactions" yaml:"interactions"`
+}
+
+// CassetteInteraction recorded request and response
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request" yaml:"request"`
+	Response CassetteResponse `json:"response" yaml:"response"`
+}
+
+// CassetteRequest recorded request
+type CassetteRequest struct {
+	Method     string      `json:"method" yaml:"method"`
+	URL        string      `json:"url" yaml:"url"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       string      `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyBase64 bool        `json:"bodyBase64,omitempty" yaml:"bodyBase64,omitempty"`
+}
+
+// CassetteResponse recorded response
+type CassetteResponse struct {
+	StatusCode int         `json:"statusCode" yaml:"statusCode"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       string      `json:"body,omitempty" yaml:"body,omitempty"`
+	BodyBase64 bool        `json:"bodyBase64,omitempty" yaml:"bodyBase64,omitempty"`
+}
+
+// RecorderMatcher match request with its body against recorded request
+type RecorderMatcher func(r *http.Request, body []byte, rec *CassetteRequest) bool
+
+// RecorderOptions options for NewRecorder
+type RecorderOptions struct {
+	Path          string                     // Path of cassette, .json use JSON, YAML otherwise
+	Mode          RecorderMode               // Mode default RecorderAuto
+	Matchers      []RecorderMatcher          // Matchers default MatchMethod and MatchURL
+	RedactHeaders []string                   // RedactHeaders replaced before save, default Authorization, Proxy-Authorization, Cookie and Set-Cookie
+	Redact        func(*CassetteInteraction) // Redact customize interaction before save, e.g. mask secret in body
+}
+
+// Recorder record interactions to cassette or replay from cassette, VCR style
+type Recorder struct {
+	o        RecorderOptions
+	mu       sync.Mutex
+	cassette *Cassette
+	used     map[*CassetteInteraction]bool
+}
+
+// NewRecorder create Recorder, load cassette when replay
+func NewRecorder(o *RecorderOptions) (*Recorder, error) {
+	if o == nil || o.Path == "" {
+		return nil, errors.New("recorder: path required")
+	}
+	rec := &Recorder{o: *o, cassette: &Cassette{Version: 1}, used: map[*CassetteInteraction]bool{}}
+	if rec.o.Matchers == nil {
+		rec.o.Matchers = []RecorderMatcher{MatchMethod, MatchURL}
+	}
+	if rec.o.RedactHeaders == nil {
+		rec.o.RedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+	}
+	if rec.o.Mode == RecorderAuto {
+		rec.o.Mode = RecorderRecord
+		if _, err := os.Stat(rec.o.Path); err == nil {
+			rec.o.Mode = RecorderReplay
+		}
+	}
+	if rec.o.Mode == RecorderReplay {
+		b, err := os.ReadFile(rec.o.Path)
+		if err != nil {
+			return nil, errors.Wrap(err, "recorder: read cassette")
+		}
+		if rec.json() {
+			err = json.Unmarshal(b, rec.cassette)
+		} else {
+			err = yaml.Unmarshal(b, rec.cassette)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "recorder: parse cassette %v", rec.o.Path)
+		}
+	}
+	return rec, nil
+}
+
+// Mode of Recorder, RecorderAuto is resolved
+func (rec *Recorder) Mode() RecorderMode {
+	return rec.o.Mode
+}
+
+// Cassette current cassette
+func (rec *Recorder) Cassette() *Cassette {
+	return rec.cassette
+}
+
+// Hook record or replay in HandleRequest
+func (rec *Recorder) Hook() Hook {
+	return Hook{
+		Name:  "Recorder",
+		Order: -2,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if rec.o.Mode == RecorderReplay {
+					return rec.replay(r)
+				}
+				return rec.record(next, r)
+			})
+		},
+	}
+}
+
+// Save cassette, called after every recorded interaction
+func (rec *Recorder) Save() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.save()
+}
+
+func (rec *Recorder) save() (err error) {
+	var b []byte
+	if rec.json() {
+		b, err = json.MarshalIndent(rec.cassette, "", "  ")
+	} else {
+		b, err = yaml.Marshal(rec.cassette)
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(rec.o.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(rec.o.Path, b, 0o644) //nolint:gosec
+}
+
+func (rec *Recorder) json() bool {
+	return strings.EqualFold(filepath.Ext(rec.o.Path), ".json")
+}
+
+func (rec *Recorder) record(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	body, err := requestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	res, err := next.RoundTrip(r)
+	if err != nil {
+		return res, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	i := &CassetteInteraction{
+		Request: CassetteRequest{
+			Method: r.Method,
+			URL:    r.URL.String(),
+			Header: r.Header.Clone(),
+		},
+		Response: CassetteResponse{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+		},
+	}
+	i.Request.Body, i.Request.BodyBase64 = encodeCassetteBody(body)
+	i.Response.Body, i.Response.BodyBase64 = encodeCassetteBody(resBody)
+	for _, h := range rec.o.RedactHeaders {
+		for _, hh := range []http.Header{i.Request.Header, i.Response.Header} {
+			if hh.Get(h) != "" {
+				hh.Set(h, "REDACTED")
+			}
+		}
+	}
+	if rec.o.Redact != nil {
+		rec.o.Redact(i)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.cassette.Interactions = append(rec.cassette.Interactions, i)
+	if err = rec.save(); err != nil {
+		_ = res.Body.Close()
+		return nil, errors.Wrap(err, "recorder: save cassette")
+	}
+	return res, nil
+}
+
+func (rec *Recorder) replay(r *http.Request) (*http.Response, error) {
+	body, err := requestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	rec.mu.Lock()
+	var found *CassetteInteraction
+	for _, i := range rec.cassette.Interactions {
+		if rec.match(r, body, &i.Request) {
+			if !rec.used[i] {
+				found = i
+				break
+			}
+			if found == nil {
+				// reuse when all matched are used
+				found = i
+			}
+		}
+	}
+	if found != nil {
+		rec.used[found] = true
+	}
+	rec.mu.Unlock()
+	if found == nil {
+		return nil, errors.Wrapf(ErrInteractionNotFound, "%v %v", r.Method, r.URL)
+	}
+
+	resBody, err := decodeCassetteBody(found.Response.Body, found.Response.BodyBase64)
+	if err != nil {
+		return nil, err
+	}
+	header := found.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", found.Response.StatusCode, http.StatusText(found.Response.StatusCode)),
+		StatusCode:    found.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(resBody)),
+		ContentLength: int64(len(resBody)),
+		Request:       r,
+	}, nil
+}
+
+func (rec *Recorder) match(r *http.Request, body []byte, i *CassetteRequest) bool {
+	for _, m := range rec.o.Matchers {
+		if !m(r, body, i) {
+			return false
+		}
+	}
+	return true
+}
+
+func encodeCassetteBody(b []byte) (string, bool) {
+	if utf8.Valid(b) {
+		return string(b), false
+	}
+	return base64.StdEncoding.EncodeToString(b), true
+}
+
+func decodeCassetteBody(s string, b64 bool) ([]byte, error) {
+	if b64 {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// MatchMethod match request method
+func MatchMethod(r *http.Request, _ []byte, rec *CassetteRequest) bool {
+	return r.Method == rec.Method
+}
+
+// MatchURL match full url
+func MatchURL(r *http.Request, _ []byte, rec *CassetteRequest) bool {
+	return r.URL.String() == rec.URL
+}
+
+// MatchQuery match url with query ignoring parameter order
+func MatchQuery(r *http.Request, _ []byte, rec *CassetteRequest) bool {
+	u, err := url.Parse(rec.URL)
+	if err != nil {
+		return false
+	}
+	if u.Scheme != r.URL.Scheme || u.Host != r.URL.Host || u.Path != r.URL.Path {
+		return false
+	}
+	return reflect.DeepEqual(u.Query(), r.URL.Query())
+}
+
+// MatchJSONBody match body as equal JSON value, compare raw bytes when body is not JSON
+func MatchJSONBody(_ *http.Request, body []byte, rec *CassetteRequest) bool {
+	recBody, err := decodeCassetteBody(rec.Body, rec.BodyBase64)
+	if err != nil {
+		return false
+	}
+	var a, b interface{}
+	if json.Unmarshal(body, &a) != nil || json.Unmarshal(recBody, &b) != nil {
+		return bytes.Equal(body, recBody)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// MatchHeaders match values of named headers
+func MatchHeaders(names ...string) RecorderMatcher {
+	return func(r *http.Request, _ []byte, rec *CassetteRequest) bool {
+		for _, v := range names {
+			if strings.Join(r.Header.Values(v), ",") != strings.Join(rec.Header.Values(v), ",") {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,93 @@
+package req_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		if r.URL.Path == "/bin" {
+			_, _ = w.Write([]byte{0xff, 0xfe})
+			return
+		}
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + string(body)))
+	}))
+	defer server.Close()
+
+	for _, name := range []string{"api.yaml", "api.json"} {
+		path := filepath.Join(t.TempDir(), "cassettes", name)
+		rec, err := req.NewRecorder(&req.RecorderOptions{
+			Path: path,
+			Redact: func(i *req.CassetteInteraction) {
+				i.Request.Body = strings.ReplaceAll(i.Request.Body, "secret", "***")
+				i.Response.Body = strings.ReplaceAll(i.Response.Body, "secret", "***")
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, req.RecorderRecord, rec.Mode())
+
+		client := req.Request{BaseURL: server.URL, Header: http.Header{"Authorization": []string{"Bearer secret"}}}.WithHook(rec.Hook())
+		out, _, err := client.With(req.Request{URL: "/a?x=1&y=2"}).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "GET /a?x=1&y=2 ", out)
+		_, _, err = client.With(req.Request{URL: "/b", Method: http.MethodPost, Body: map[string]interface{}{"a": 1, "b": "secret"}}).WithHook(req.JSONEncode).FetchString()
+		assert.NoError(t, err)
+		bin, _, err := client.With(req.Request{URL: "/bin"}).FetchBytes()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xff, 0xfe}, bin)
+
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(b), "secret")
+		assert.Contains(t, string(b), "REDACTED")
+
+		// replay
+		rec, err = req.NewRecorder(&req.RecorderOptions{
+			Path:     path,
+			Matchers: []req.RecorderMatcher{req.MatchMethod, req.MatchQuery, req.MatchJSONBody, req.MatchHeaders("Authorization")},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, req.RecorderReplay, rec.Mode())
+		assert.Len(t, rec.Cassette().Interactions, 3)
+
+		replay := req.Request{BaseURL: server.URL, Header: http.Header{"Authorization": []string{"REDACTED"}}}.WithHook(rec.Hook(), req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+			t.Fatal("should not send request")
+			return nil, nil
+		})))
+		out, res, err := replay.With(req.Request{URL: "/a?y=2&x=1"}).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "GET /a?x=1&y=2 ", out)
+		assert.Equal(t, "REDACTED", res.Header.Get("Set-Cookie"))
+		// reuse
+		out, _, err = replay.With(req.Request{URL: "/a?y=2&x=1"}).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "GET /a?x=1&y=2 ", out)
+
+		out, _, err = replay.With(req.Request{URL: "/b", Method: http.MethodPost, RawBody: []byte(`{"b":"***","a":1}`)}).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, `POST /b {"a":1,"b":"***"}`, out)
+		bin, _, err = replay.With(req.Request{URL: "/bin"}).FetchBytes()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0xff, 0xfe}, bin)
+
+		_, err = replay.With(req.Request{URL: "/b", Method: http.MethodPost, RawBody: []byte(`{"a":2}`)}).Do()
+		assert.True(t, errors.Is(err, req.ErrInteractionNotFound))
+		_, err = replay.With(req.Request{URL: "/a?x=1", Header: http.Header{"Authorization": []string{"other"}}}).Do()
+		assert.True(t, errors.Is(err, req.ErrInteractionNotFound))
+	}
+
+	_, err := req.NewRecorder(&req.RecorderOptions{Path: filepath.Join(t.TempDir(), "none.yaml"), Mode: req.RecorderReplay})
+	assert.Error(t, err)
+}
@@ -0,0 +1,394 @@
+// Package reqtest provide testing helpers for req
+package reqtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/wenerme/go-req"
+)
+
+// TestingT subset of testing.TB used by Mock
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Mock http.RoundTripper serve registered expectations
+type Mock struct {
+	t            TestingT
+	mu           sync.Mutex
+	ordered      bool
+	cursor       int
+	expectations []*Expectation
+}
+
+// NewMock create Mock report failures to t
+func NewMock(t TestingT) *Mock {
+	return &Mock{t: t}
+}
+
+// Ordered require expectations matched in registration order
+func (m *Mock) Ordered() *Mock {
+	m.ordered = true
+	return m
+}
+
+// On register Expectation of method and path pattern, pattern use path.Match syntax, empty method or * match any method
+func (m *Mock) On(method string, pattern string) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := &Expectation{mock: m, method: method, pattern: pattern, times: -1, status: http.StatusOK, header: http.Header{}}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// Hook use Mock as transport
+func (m *Mock) Hook() req.Hook {
+	return req.UseRoundTripper(m)
+}
+
+// RoundTrip serve request by first matched Expectation, unmatched request fail the test
+func (m *Mock) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, err
+		}
+		_ = r.Body.Close()
+	}
+
+	m.mu.Lock()
+	e, diff := m.find(r, body)
+	if e != nil {
+		e.calls++
+	}
+	m.mu.Unlock()
+
+	if e == nil {
+		m.t.Helper()
+		m.t.Errorf("reqtest: unmatched request %s %s\n%s", r.Method, r.URL, diff)
+		return nil, fmt.Errorf("reqtest: unmatched request %s %s", r.Method, r.URL)
+	}
+	return e.respond(r, body)
+}
+
+func (m *Mock) find(r *http.Request, body []byte) (*Expectation, string) {
+	sb := strings.Builder{}
+	if m.ordered {
+		for i := m.cursor; i < len(m.expectations); i++ {
+			e := m.expectations[i]
+			if e.exhausted() {
+				m.cursor = i + 1
+				continue
+			}
+			mismatches := e.mismatch(r, body)
+			if len(mismatches) == 0 {
+				// expectations before are satisfied
+				m.cursor = i
+				return e, ""
+			}
+			writeMismatch(&sb, e, mismatches)
+			if !e.satisfied() {
+				break
+			}
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("  no pending expectation\n")
+		}
+		return nil, sb.String()
+	}
+
+	for _, e := range m.expectations {
+		if e.exhausted() {
+			continue
+		}
+		mismatches := e.mismatch(r, body)
+		if len(mismatches) == 0 {
+			return e, ""
+		}
+		writeMismatch(&sb, e, mismatches)
+	}
+	if sb.Len() == 0 {
+		sb.WriteString("  no pending expectation\n")
+	}
+	return nil, sb.String()
+}
+
+func writeMismatch(sb *strings.Builder, e *Expectation, mismatches []string) {
+	_, _ = fmt.Fprintf(sb, "  %s:\n", e)
+	for _, v := range mismatches {
+		_, _ = fmt.Fprintf(sb, "    - %s\n", v)
+	}
+}
+
+// LoadHAR register entries of HAR as expectations match method, path and query, reply recorded response.
+// Each entry is matched once, so repeated requests replay in recorded order.
+func (m *Mock) LoadHAR(h *req.HAR) error {
+	for _, v := range h.Log.Entries {
+		u, err := url.Parse(v.Request.URL)
+		if err != nil {
+			return err
+		}
+		body, err := v.Response.Content.Bytes()
+		if err != nil {
+			return err
+		}
+		e := m.On(v.Request.Method, u.Path).Once()
+		for _, q := range v.Request.QueryString {
+			e.WithQuery(q.Name, q.Value)
+		}
+		for _, h := range v.Response.Headers {
+			switch http.CanonicalHeaderKey(h.Name) {
+			case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+				continue
+			}
+			e.ReplyHeader(h.Name, h.Value)
+		}
+		e.Reply(v.Response.Status, body)
+	}
+	return nil
+}
+
+// AssertExpectations check all expectations called as required
+func (m *Mock) AssertExpectations() bool {
+	m.t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ok := true
+	for _, e := range m.expectations {
+		if !e.satisfied() {
+			ok = false
+			if e.times < 0 {
+				m.t.Errorf("reqtest: expected %s to be called", e)
+			} else {
+				m.t.Errorf("reqtest: expected %s to be called %d times, got %d", e, e.times, e.calls)
+			}
+		}
+	}
+	return ok
+}
+
+// Expectation of request and its response
+type Expectation struct {
+	mock     *Mock
+	method   string
+	pattern  string
+	query    [][2]string
+	headers  [][2]string
+	json     interface{}
+	hasJSON  bool
+	matchers []func(r *http.Request) bool
+	times    int
+	calls    int
+	status   int
+	header   http.Header
+	body     []byte
+	handler  http.Handler
+}
+
+// WithQuery require query parameter
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	e.query = append(e.query, [2]string{key, value})
+	return e
+}
+
+// WithHeader require header value
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.headers = append(e.headers, [2]string{key, value})
+	return e
+}
+
+// WithJSON require body equal to v as JSON
+func (e *Expectation) WithJSON(v interface{}) *Expectation {
+	e.json = normalizeJSON(v)
+	e.hasJSON = true
+	return e
+}
+
+// Match require custom matcher
+func (e *Expectation) Match(fn func(r *http.Request) bool) *Expectation {
+	e.matchers = append(e.matchers, fn)
+	return e
+}
+
+// Reply with status and body, body can be string, []byte or value encoded as JSON
+func (e *Expectation) Reply(status int, body interface{}) *Expectation {
+	e.status = status
+	switch v := body.(type) {
+	case nil:
+		e.body = nil
+	case string:
+		e.body = []byte(v)
+	case []byte:
+		e.body = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(err)
+		}
+		e.body = b
+		if e.header.Get("Content-Type") == "" {
+			e.header.Set("Content-Type", "application/json")
+		}
+	}
+	return e
+}
+
+// ReplyHeader add response header
+func (e *Expectation) ReplyHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// Handle serve matched request by handler
+func (e *Expectation) Handle(h http.HandlerFunc) *Expectation {
+	e.handler = h
+	return e
+}
+
+// Times require exactly n calls, default at least once
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Once is Times(1)
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Calls matched count, safe to call while requests in flight
+func (e *Expectation) Calls() int {
+	e.mock.mu.Lock()
+	defer e.mock.mu.Unlock()
+	return e.calls
+}
+
+func (e *Expectation) String() string {
+	s := e.method
+	if s == "" {
+		s = "*"
+	}
+	s += " " + e.pattern
+	for i, v := range e.query {
+		if i == 0 {
+			s += "?"
+		} else {
+			s += "&"
+		}
+		s += v[0] + "=" + v[1]
+	}
+	return s
+}
+
+func (e *Expectation) exhausted() bool {
+	return e.times >= 0 && e.calls >= e.times
+}
+
+func (e *Expectation) satisfied() bool {
+	if e.times < 0 {
+		return e.calls > 0
+	}
+	return e.calls == e.times
+}
+
+// mismatch reasons of request not match
+func (e *Expectation) mismatch(r *http.Request, body []byte) (reasons []string) {
+	if e.method != "" && e.method != "*" && !strings.EqualFold(e.method, r.Method) {
+		reasons = append(reasons, fmt.Sprintf("method: want %s, got %s", e.method, r.Method))
+	}
+	if ok, _ := path.Match(e.pattern, r.URL.Path); !ok {
+		reasons = append(reasons, fmt.Sprintf("path: want %s, got %s", e.pattern, r.URL.Path))
+	}
+	q := r.URL.Query()
+	for _, v := range e.query {
+		if got, ok := q[v[0]]; !ok || !contains(got, v[1]) {
+			reasons = append(reasons, fmt.Sprintf("query %s: want %q, got %q", v[0], v[1], strings.Join(got, ",")))
+		}
+	}
+	for _, v := range e.headers {
+		if got := r.Header.Values(v[0]); !contains(got, v[1]) {
+			reasons = append(reasons, fmt.Sprintf("header %s: want %q, got %q", v[0], v[1], strings.Join(got, ",")))
+		}
+	}
+	if e.hasJSON {
+		var got interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			reasons = append(reasons, fmt.Sprintf("json body: invalid %q", body))
+		} else if !reflect.DeepEqual(e.json, got) {
+			want, _ := json.Marshal(e.json)
+			reasons = append(reasons, fmt.Sprintf("json body: want %s, got %s", want, bytes.TrimSpace(body)))
+		}
+	}
+	for i, fn := range e.matchers {
+		if !fn(r) {
+			reasons = append(reasons, fmt.Sprintf("matcher #%d: not matched", i))
+		}
+	}
+	return reasons
+}
+
+func (e *Expectation) respond(r *http.Request, body []byte) (*http.Response, error) {
+	if e.handler != nil {
+		rr := r.Clone(r.Context())
+		rr.Body = io.NopCloser(bytes.NewReader(body))
+		rr.RequestURI = r.URL.RequestURI()
+		w := httptest.NewRecorder()
+		e.handler.ServeHTTP(w, rr)
+		res := w.Result()
+		res.Request = r
+		return res, nil
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       r,
+	}, nil
+}
+
+func contains(s []string, v string) bool {
+	for _, vv := range s {
+		if vv == v {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeJSON convert v to generic JSON value for comparison
+func normalizeJSON(v interface{}) interface{} {
+	var b []byte
+	switch vv := v.(type) {
+	case string:
+		b = []byte(vv)
+	case []byte:
+		b = vv
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			panic(err)
+		}
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		panic(err)
+	}
+	return out
+}
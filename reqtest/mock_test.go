@@ -0,0 +1,176 @@
+package reqtest_test
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+	"github.com/wenerme/go-req/reqtest"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMock(t *testing.T) {
+	m := reqtest.NewMock(t)
+	m.On(http.MethodGet, "/users/*").WithQuery("fields", "name").Reply(http.StatusOK, map[string]string{"name": "wener"})
+	m.On(http.MethodPost, "/users").WithHeader("X-Token", "t").WithJSON(`{"name":"wener","age":1}`).Reply(http.StatusCreated, "created").Once()
+	m.On("*", "/echo").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		_, _ = w.Write([]byte(r.URL.RequestURI()))
+	}).Times(2)
+
+	client := req.Request{BaseURL: "http://api"}.WithHook(m.Hook())
+	var out struct {
+		Name string
+	}
+	err := client.With(req.Request{URL: "/users/1", Query: map[string]string{"fields": "name"}}).WithHook(req.JSONDecode).Fetch(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, "wener", out.Name)
+
+	s, res, err := client.With(req.Request{
+		URL:    "/users",
+		Method: http.MethodPost,
+		Header: http.Header{"X-Token": []string{"t"}},
+		Body:   map[string]interface{}{"age": 1, "name": "wener"},
+	}).WithHook(req.JSONEncode).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.Equal(t, "created", s)
+
+	s, res, err = client.With(req.Request{URL: "/echo?a=1", Method: http.MethodDelete}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "/echo?a=1", s)
+	assert.Equal(t, http.MethodDelete, res.Header.Get("X-Method"))
+	_, err = client.With(req.Request{URL: "/echo"}).Do()
+	assert.NoError(t, err)
+	assert.True(t, m.AssertExpectations())
+}
+
+func TestMockConcurrentCalls(t *testing.T) {
+	m := reqtest.NewMock(t)
+	e := m.On(http.MethodGet, "/ping").Reply(http.StatusOK, "pong")
+	client := req.Request{BaseURL: "http://api"}.WithHook(m.Hook())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.With(req.Request{URL: "/ping"}).Do()
+			assert.NoError(t, err)
+		}()
+	}
+	// read while requests in flight, checked by -race
+	for e.Calls() < 10 {
+		runtime.Gosched()
+	}
+	wg.Wait()
+	assert.Equal(t, 10, e.Calls())
+	m.AssertExpectations()
+}
+
+func TestMockFailures(t *testing.T) {
+	ft := &fakeT{}
+	m := reqtest.NewMock(ft)
+	m.On(http.MethodPost, "/users").WithJSON(map[string]int{"a": 1}).Once()
+	m.On(http.MethodGet, "/never")
+	client := req.Request{BaseURL: "http://api"}.WithHook(m.Hook())
+
+	_, err := client.With(req.Request{URL: "/users", Method: http.MethodPost, RawBody: []byte(`{"a":2}`)}).Do()
+	assert.Error(t, err)
+	assert.Len(t, ft.errors, 1)
+	assert.Contains(t, ft.errors[0], "unmatched request POST http://api/users")
+	assert.Contains(t, ft.errors[0], `json body: want {"a":1}, got {"a":2}`)
+	assert.Contains(t, ft.errors[0], "method: want GET, got POST")
+	assert.Contains(t, ft.errors[0], "path: want /never, got /users")
+
+	_, err = client.With(req.Request{URL: "/users", Method: http.MethodPost, RawBody: []byte(`{"a":1}`)}).Do()
+	assert.NoError(t, err)
+	// exhausted
+	_, err = client.With(req.Request{URL: "/users", Method: http.MethodPost, RawBody: []byte(`{"a":1}`)}).Do()
+	assert.Error(t, err)
+
+	ft.errors = nil
+	assert.False(t, m.AssertExpectations())
+	assert.Equal(t, []string{"reqtest: expected GET /never to be called"}, ft.errors)
+}
+
+func TestMockOrdered(t *testing.T) {
+	ft := &fakeT{}
+	m := reqtest.NewMock(ft).Ordered()
+	m.On(http.MethodGet, "/a").Once()
+	m.On(http.MethodGet, "/b")
+	m.On(http.MethodGet, "/c").Once()
+	client := req.Request{BaseURL: "http://api"}.WithHook(m.Hook())
+
+	_, err := client.With(req.Request{URL: "/b"}).Do()
+	assert.Error(t, err)
+	for _, v := range []string{"/a", "/b", "/b", "/c"} {
+		_, err = client.With(req.Request{URL: v}).Do()
+		assert.NoError(t, err, v)
+	}
+	_, err = client.With(req.Request{URL: "/a"}).Do()
+	assert.Error(t, err)
+	assert.Len(t, ft.errors, 2)
+	assert.Contains(t, ft.errors[1], "no pending expectation")
+	assert.True(t, m.AssertExpectations())
+}
+
+func TestMockLoadHAR(t *testing.T) {
+	rec := req.NewHARRecorder()
+	src := reqtest.NewMock(t)
+	src.On(http.MethodGet, "/users").WithQuery("page", "1").Reply(http.StatusOK, `[{"name":"wener"}]`).ReplyHeader("Content-Type", "application/json")
+	src.On(http.MethodDelete, "/users/1").Reply(http.StatusNoContent, nil)
+	client := req.Request{BaseURL: "http://api"}.WithHook(src.Hook(), rec.Hook())
+	_, err := client.With(req.Request{URL: "/users?page=1"}).Do()
+	assert.NoError(t, err)
+	_, err = client.With(req.Request{URL: "/users/1", Method: http.MethodDelete}).Do()
+	assert.NoError(t, err)
+
+	m := reqtest.NewMock(t)
+	assert.NoError(t, m.LoadHAR(rec.HAR()))
+	replay := req.Request{BaseURL: "http://api"}.WithHook(m.Hook())
+	out, res, err := replay.With(req.Request{URL: "/users?page=1"}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"name":"wener"}]`, out)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	_, res, err = replay.With(req.Request{URL: "/users/1", Method: http.MethodDelete}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.True(t, m.AssertExpectations())
+}
+
+func TestMockLoadHARDuplicate(t *testing.T) {
+	rec := req.NewHARRecorder()
+	src := reqtest.NewMock(t)
+	for _, v := range []string{"1", "2", "3"} {
+		src.On(http.MethodGet, "/counter").Once().Reply(http.StatusOK, v)
+	}
+	client := req.Request{URL: "http://api/counter"}.WithHook(src.Hook(), rec.Hook())
+	for i := 0; i < 3; i++ {
+		_, err := client.Do()
+		assert.NoError(t, err)
+	}
+
+	m := reqtest.NewMock(t)
+	assert.NoError(t, m.LoadHAR(rec.HAR()))
+	replay := req.Request{URL: "http://api/counter"}.WithHook(m.Hook())
+	for _, expect := range []string{"1", "2", "3"} {
+		out, _, err := replay.FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, expect, out)
+	}
+	assert.True(t, m.AssertExpectations())
+}
@@ -165,14 +165,24 @@ func (r Request) NewRequest() (*http.Request, error) {
 	if err := r.Reconcile(); err != nil {
 		return nil, err
 	}
+	ctx := NewContext(r.Context, &r)
+	var contentType string
 	if r.RawBody == nil && r.GetBody == nil && r.Body != nil {
-		r.RawBody, r.LastError = r.Extension.Encode(r.Context, r.Body)
+		r.RawBody, contentType, r.LastError = r.Extension.EncodeContent(ctx, r.Body)
 	}
 	if r.LastError != nil {
 		return nil, r.LastError
 	}
+	if contentType != "" && r.Header.Get("Content-Type") == "" {
+		// header may be shared with the origin Request
+		r.Header = r.Header.Clone()
+		if r.Header == nil {
+			r.Header = http.Header{}
+		}
+		r.Header.Set("Content-Type", contentType)
+	}
 
-	req, err := http.NewRequestWithContext(NewContext(r.Context, &r), r.Method, r.URL, nil)
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -245,6 +255,11 @@ func (r *Request) Reconcile() error {
 		r.RawQuery = v.Encode()
 	}
 
+	if strings.HasPrefix(r.BaseURL, "unix://") {
+		r.Extension.With(UnixSocketHook(strings.TrimPrefix(r.BaseURL, "unix://")))
+		r.BaseURL = "http://localhost"
+	}
+
 	{
 		u := r.URL
 		if strings.HasPrefix(u, "/") && r.BaseURL != "" {
@@ -0,0 +1,131 @@
+package req
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResolveOptions options for ResolveHook
+type ResolveOptions struct {
+	Hosts    map[string][]string // Hosts map host:port or host to addresses, address without port use the requested port
+	CacheTTL time.Duration       // CacheTTL cache DNS result of other hosts when > 0
+	Resolver *net.Resolver       // Resolver for cached lookup, default net.DefaultResolver
+}
+
+// ResolveHook override host resolution like curl --resolve, TLS SNI and Host header are kept
+func ResolveHook(o *ResolveOptions) Hook {
+	if o == nil {
+		o = &ResolveOptions{}
+	}
+	c := &resolveCache{ttl: o.CacheTTL, resolver: o.Resolver, entries: map[string]resolveEntry{}}
+	if c.resolver == nil {
+		c.resolver = net.DefaultResolver
+	}
+	h := transportHook("Resolve", 100, func(t *http.Transport) error {
+		dial := transportDial(t)
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return dial(ctx, network, addr)
+			}
+			addrs, ok := o.Hosts[addr]
+			if !ok {
+				addrs, ok = o.Hosts[host]
+			}
+			if !ok && c.ttl > 0 && net.ParseIP(host) == nil {
+				if addrs, err = c.lookup(ctx, host); err != nil {
+					return nil, err
+				}
+			}
+			if len(addrs) == 0 {
+				return dial(ctx, network, addr)
+			}
+			var lastErr error
+			for _, v := range addrs {
+				if _, _, err := net.SplitHostPort(v); err != nil {
+					v = net.JoinHostPort(v, port)
+				}
+				conn, err := dial(ctx, network, v)
+				if err == nil {
+					return conn, nil
+				}
+				lastErr = err
+			}
+			return nil, lastErr
+		}
+		return nil
+	})
+	h.curl = o.curl
+	return h
+}
+
+// curl --resolve for ip addresses, --connect-to the first address otherwise
+func (o ResolveOptions) curl(r *http.Request) (args, comments []string) {
+	host, port := r.URL.Hostname(), r.URL.Port()
+	if port == "" {
+		port = "80"
+		if r.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	addrs, ok := o.Hosts[net.JoinHostPort(host, port)]
+	if !ok {
+		addrs = o.Hosts[host]
+	}
+	if len(addrs) == 0 {
+		return
+	}
+	ips := make([]string, 0, len(addrs))
+	for _, v := range addrs {
+		if ip := net.ParseIP(v); ip != nil {
+			if ip.To4() == nil {
+				v = "[" + v + "]"
+			}
+			ips = append(ips, v)
+		}
+	}
+	if len(ips) == len(addrs) {
+		return []string{"--resolve " + shellQuote(host+":"+port+":"+strings.Join(ips, ","))}, nil
+	}
+	to, toPort, err := net.SplitHostPort(addrs[0])
+	if err != nil {
+		to, toPort = addrs[0], port
+	}
+	if strings.Contains(to, ":") {
+		to = "[" + to + "]"
+	}
+	return []string{"--connect-to " + shellQuote(host+":"+port+":"+to+":"+toPort)}, nil
+}
+
+type resolveEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+type resolveCache struct {
+	ttl      time.Duration
+	resolver *net.Resolver
+	mu       sync.Mutex
+	entries  map[string]resolveEntry
+}
+
+func (c *resolveCache) lookup(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	e, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.addrs, nil
+	}
+	addrs, err := c.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[host] = resolveEntry{addrs: addrs, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return addrs, nil
+}
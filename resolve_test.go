@@ -0,0 +1,77 @@
+package req_test
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestResolveHook(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	u, _ := url.Parse(server.URL)
+	{
+		out, _, err := req.Request{URL: "http://api.example.com:" + u.Port()}.WithHook(req.ResolveHook(&req.ResolveOptions{
+			Hosts: map[string][]string{"api.example.com:" + u.Port(): {"127.0.0.2:1", "127.0.0.1"}},
+		})).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "api.example.com:"+u.Port(), out)
+	}
+	{
+		out, _, err := req.Request{URL: "http://localhost:" + u.Port()}.WithHook(req.ResolveHook(&req.ResolveOptions{
+			CacheTTL: time.Minute,
+		})).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost:"+u.Port(), out)
+	}
+	{
+		// certificate of httptest is issued for example.com
+		tu, _ := url.Parse(tlsServer.URL)
+		out, _, err := req.Request{URL: "https://example.com:" + tu.Port()}.WithHook(
+			req.ResolveHook(&req.ResolveOptions{Hosts: map[string][]string{"example.com": {"127.0.0.1"}}}),
+			req.TLSHook(&req.TLSOptions{RootCAPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})}),
+		).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com:"+tu.Port(), out)
+	}
+}
+
+func TestResolveHookWithDial(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	var dialed []string
+	for _, order := range []bool{true, false} {
+		dial := req.DialHook(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, addr)
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		})
+		resolve := req.ResolveHook(&req.ResolveOptions{Hosts: map[string][]string{"api.example.com": {"127.0.0.1"}}})
+		client := req.Request{URL: "http://api.example.com:" + u.Port()}.WithHook(resolve).WithHook(dial)
+		if order {
+			client = req.Request{URL: "http://api.example.com:" + u.Port()}.WithHook(dial).WithHook(resolve)
+		}
+		dialed = nil
+		out, _, err := client.FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "api.example.com:"+u.Port(), out)
+		assert.Equal(t, []string{"127.0.0.1:" + u.Port()}, dialed)
+	}
+}
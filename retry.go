@@ -0,0 +1,158 @@
+package req
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryOptions options for RetryHook
+type RetryOptions struct {
+	MaxRetries  int                                      `yaml:"maxRetries"` // MaxRetries after first attempt, default 3, negative disable retry
+	Backoff     time.Duration                            `yaml:"backoff"`    // Backoff before first retry, doubled for each retry with jitter, default 100ms
+	MaxBackoff  time.Duration                            `yaml:"maxBackoff"` // MaxBackoff cap of backoff and Retry-After, default 10s
+	Status      []int                                    `yaml:"status"`     // Status retry on response status, default 429, 502, 503 and 504
+	Methods     []string                                 `yaml:"methods"`    // Methods allow retry, default idempotent methods
+	ShouldRetry func(res *http.Response, err error) bool `yaml:"-"`          // ShouldRetry override default check of error and Status
+}
+
+// RetryHook retry failed request with exponential backoff, Retry-After of response is respected.
+//
+// Request with body is retried only when GetBody is available.
+func RetryHook(o *RetryOptions) Hook {
+	if o == nil {
+		o = &RetryOptions{}
+	}
+	rt := &retrier{RetryOptions: *o}
+	switch {
+	case rt.MaxRetries == 0:
+		rt.MaxRetries = 3 //nolint:gomnd
+	case rt.MaxRetries < 0:
+		rt.MaxRetries = 0
+	}
+	if rt.Backoff <= 0 {
+		rt.Backoff = 100 * time.Millisecond //nolint:gomnd
+	}
+	if rt.MaxBackoff <= 0 {
+		rt.MaxBackoff = 10 * time.Second //nolint:gomnd
+	}
+	if len(rt.Status) == 0 {
+		rt.Status = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	if len(rt.Methods) == 0 {
+		rt.Methods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete}
+	}
+	return Hook{
+		Name:  "Retry",
+		Order: -45,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return rt.roundTrip(next, r)
+			})
+		},
+	}
+}
+
+type retrier struct {
+	RetryOptions
+}
+
+func (rt *retrier) retryable(r *http.Request) bool {
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+	for _, v := range rt.Methods {
+		if strings.EqualFold(v, r.Method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rt *retrier) shouldRetry(res *http.Response, err error) bool {
+	if rt.ShouldRetry != nil {
+		return rt.ShouldRetry(res, err)
+	}
+	if err != nil {
+		return true
+	}
+	return containsInt(rt.Status, res.StatusCode)
+}
+
+func (rt *retrier) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	if !rt.retryable(r) {
+		return next.RoundTrip(r)
+	}
+	ctx := r.Context()
+	for attempt := 0; ; attempt++ {
+		req := r
+		if attempt > 0 {
+			req = r.Clone(ctx)
+			if r.GetBody != nil {
+				body, err := r.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+		res, err := next.RoundTrip(req)
+		if attempt >= rt.MaxRetries || ctx.Err() != nil || !rt.shouldRetry(res, err) {
+			return res, err
+		}
+
+		wait := rt.backoff(attempt, res)
+		if res != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+			_ = res.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// backoff of attempt, jitter in [d/2, d), Retry-After take precedence
+func (rt *retrier) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if d, ok := retryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			if d > rt.MaxBackoff {
+				d = rt.MaxBackoff
+			}
+			return d
+		}
+	}
+	d := rt.Backoff
+	for i := 0; i < attempt && d < rt.MaxBackoff; i++ {
+		d *= 2 //nolint:gomnd
+	}
+	if d > rt.MaxBackoff {
+		d = rt.MaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1)) //nolint:gosec
+}
+
+// retryAfter parse Retry-After of delay seconds or http date
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
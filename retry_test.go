@@ -0,0 +1,117 @@
+package req_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestRetryHook(t *testing.T) {
+	var calls int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		switch r.URL.Path {
+		case "/flaky":
+			if n < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/after":
+			if n < 2 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		case "/down":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case "/bad":
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := req.Request{BaseURL: server.URL}.WithHook(req.RetryHook(&req.RetryOptions{Backoff: time.Millisecond}))
+	reset := func() {
+		atomic.StoreInt32(&calls, 0)
+		bodies = nil
+	}
+
+	s, res, err := client.With(req.Request{URL: "/flaky"}).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", s)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int32(3), calls)
+
+	reset()
+	_, err = client.With(req.Request{URL: "/after"}).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), calls)
+
+	// give up after MaxRetries
+	reset()
+	res, err = client.With(req.Request{URL: "/down"}).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	assert.Equal(t, int32(4), calls)
+
+	// disabled
+	reset()
+	res, err = req.Request{BaseURL: server.URL, URL: "/down"}.WithHook(req.RetryHook(&req.RetryOptions{MaxRetries: -1})).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	assert.Equal(t, int32(1), calls)
+
+	// not retry status
+	reset()
+	res, err = client.With(req.Request{URL: "/bad"}).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, int32(1), calls)
+
+	// not idempotent
+	reset()
+	res, err = client.With(req.Request{URL: "/flaky", Method: http.MethodPost}).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, int32(1), calls)
+
+	// body is replayed
+	reset()
+	_, err = client.With(req.Request{URL: "/flaky", Method: http.MethodPut, RawBody: []byte("data")}).Do()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data", "data", "data"}, bodies)
+}
+
+func TestRetryHookError(t *testing.T) {
+	var calls int32
+	failure := errors.New("connect failed")
+	fail := req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, failure
+	}))
+	client := req.Request{URL: "http://127.0.0.1"}.WithHook(fail, req.RetryHook(&req.RetryOptions{MaxRetries: 2, Backoff: time.Millisecond}))
+	_, err := client.Do()
+	assert.True(t, errors.Is(err, failure))
+	assert.Equal(t, int32(3), calls)
+
+	calls = 0
+	client = req.Request{URL: "http://127.0.0.1"}.WithHook(fail, req.RetryHook(&req.RetryOptions{
+		ShouldRetry: func(res *http.Response, err error) bool { return false },
+	}))
+	_, err = client.Do()
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls)
+}
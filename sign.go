@@ -0,0 +1,432 @@
+package req
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignContext is the view of request used to build the string to sign
+type SignContext struct {
+	Request   *http.Request
+	Body      []byte     // Body encoded request body
+	Params    url.Values // Params query and form body params
+	Timestamp time.Time
+	Nonce     string
+}
+
+// SetParam set query param of request and Params
+func (s *SignContext) SetParam(k, v string) {
+	q := s.Request.URL.Query()
+	q.Set(k, v)
+	s.Request.URL.RawQuery = q.Encode()
+	s.Params.Set(k, v)
+}
+
+// SignPart build one part of the canonical string
+type SignPart func(s *SignContext) (string, error)
+
+// SignOptions declare how a request is canonicalized and signed
+type SignOptions struct {
+	Parts     []SignPart                                        // Parts of canonical string, joined by Separator
+	Separator string                                            // Separator of Parts, default "\n"
+	Terminate bool                                              // Terminate append Separator after last part
+	Canonical func(s *SignContext) (string, error)              // Canonical override Parts
+	Prepare   func(s *SignContext) error                        // Prepare run before canonicalize, e.g. add common params
+	Key       []byte                                            // Key of HMAC
+	Hash      func() hash.Hash                                  // Hash of HMAC, default sha256
+	Signer    func(s *SignContext, data []byte) ([]byte, error) // Signer override HMAC, e.g. RSA
+	Encode    func(b []byte) string                             // Encode signature, default hex
+	Header    string                                            // Header to set signature by default Apply, default Signature
+	Apply     func(s *SignContext, sig string) error            // Apply signature to request
+	Now       func() time.Time                                  // Now for Timestamp, default time.Now
+	Nonce     func() string                                     // Nonce generator, default random hex
+}
+
+// SignHook sign request after encoded
+func SignHook(o *SignOptions) Hook {
+	if o == nil {
+		o = &SignOptions{}
+	}
+	return Hook{
+		Name:  "Sign",
+		Order: -10,
+		OnRequest: func(r *http.Request) error {
+			return o.sign(r)
+		},
+	}
+}
+
+func (o *SignOptions) sign(r *http.Request) error {
+	body, err := requestBody(r)
+	if err != nil {
+		return err
+	}
+	params, err := requestParams(r, body)
+	if err != nil {
+		return err
+	}
+	now := time.Now
+	if o.Now != nil {
+		now = o.Now
+	}
+	nonce := newNonce
+	if o.Nonce != nil {
+		nonce = o.Nonce
+	}
+	s := &SignContext{
+		Request:   r,
+		Body:      body,
+		Params:    params,
+		Timestamp: now(),
+		Nonce:     nonce(),
+	}
+	if o.Prepare != nil {
+		if err = o.Prepare(s); err != nil {
+			return err
+		}
+	}
+
+	var canonical string
+	if o.Canonical != nil {
+		canonical, err = o.Canonical(s)
+	} else {
+		canonical, err = o.join(s)
+	}
+	if err != nil {
+		return err
+	}
+
+	var sig []byte
+	if o.Signer != nil {
+		sig, err = o.Signer(s, []byte(canonical))
+		if err != nil {
+			return err
+		}
+	} else {
+		h := o.Hash
+		if h == nil {
+			h = sha256.New
+		}
+		sig = hmacSum(h, o.Key, []byte(canonical))
+	}
+
+	encoded := hex.EncodeToString(sig)
+	if o.Encode != nil {
+		encoded = o.Encode(sig)
+	}
+	if o.Apply != nil {
+		return o.Apply(s, encoded)
+	}
+	name := o.Header
+	if name == "" {
+		name = "Signature"
+	}
+	r.Header.Set(name, encoded)
+	return nil
+}
+
+func (o *SignOptions) join(s *SignContext) (string, error) {
+	sep := o.Separator
+	if sep == "" {
+		sep = "\n"
+	}
+	sb := strings.Builder{}
+	for i, v := range o.Parts {
+		part, err := v(s)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteString(part)
+	}
+	if o.Terminate {
+		sb.WriteString(sep)
+	}
+	return sb.String(), nil
+}
+
+// SignMethod canonicalize request method
+func SignMethod(s *SignContext) (string, error) {
+	return s.Request.Method, nil
+}
+
+// SignPath canonicalize escaped path
+func SignPath(s *SignContext) (string, error) {
+	return s.Request.URL.EscapedPath(), nil
+}
+
+// SignRequestURI canonicalize path with query
+func SignRequestURI(s *SignContext) (string, error) {
+	return s.Request.URL.RequestURI(), nil
+}
+
+// SignQuery canonicalize sorted and escaped query
+func SignQuery(s *SignContext) (string, error) {
+	return s.Request.URL.Query().Encode(), nil
+}
+
+// SignParams canonicalize sorted query and form params as unescaped k=v joined by &, empty value is skipped
+func SignParams(s *SignContext) (string, error) {
+	return sortedParams(s.Params, nil), nil
+}
+
+// SignTimestamp canonicalize timestamp as unix seconds
+func SignTimestamp(s *SignContext) (string, error) {
+	return strconv.FormatInt(s.Timestamp.Unix(), 10), nil
+}
+
+// SignNonce canonicalize nonce
+func SignNonce(s *SignContext) (string, error) {
+	return s.Nonce, nil
+}
+
+// SignBody canonicalize raw body
+func SignBody(s *SignContext) (string, error) {
+	return string(s.Body), nil
+}
+
+// SignBodyHash canonicalize hex encoded hash of body
+func SignBodyHash(h func() hash.Hash) SignPart {
+	return func(s *SignContext) (string, error) {
+		return hashHex(h, s.Body), nil
+	}
+}
+
+// SignHeaders canonicalize headers as lower-cased name:value lines
+func SignHeaders(names ...string) SignPart {
+	return func(s *SignContext) (string, error) {
+		lines := make([]string, 0, len(names))
+		for _, v := range names {
+			lines = append(lines, strings.ToLower(v)+":"+strings.TrimSpace(requestHeader(s.Request, v)))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+// SignValue canonicalize constant value
+func SignValue(v string) SignPart {
+	return func(s *SignContext) (string, error) {
+		return v, nil
+	}
+}
+
+// WechatPaySignOptions preset for WeChat Pay API v3
+func WechatPaySignOptions(mchID, serialNo string, key *rsa.PrivateKey) *SignOptions {
+	return &SignOptions{
+		Parts:     []SignPart{SignMethod, SignRequestURI, SignTimestamp, SignNonce, SignBody},
+		Terminate: true,
+		Signer:    rsaSHA256Signer(key),
+		Encode:    base64.StdEncoding.EncodeToString,
+		Apply: func(s *SignContext, sig string) error {
+			s.Request.Header.Set("Authorization", fmt.Sprintf(
+				`WECHATPAY2-SHA256-RSA2048 mchid="%s",nonce_str="%s",signature="%s",timestamp="%d",serial_no="%s"`,
+				mchID, s.Nonce, sig, s.Timestamp.Unix(), serialNo,
+			))
+			return nil
+		},
+	}
+}
+
+// AlipaySignOptions preset for Alipay OpenAPI with RSA2, common params are added to query
+func AlipaySignOptions(appID string, key *rsa.PrivateKey) *SignOptions {
+	cst := time.FixedZone("CST", 8*60*60) //nolint:gomnd
+	return &SignOptions{
+		Prepare: func(s *SignContext) error {
+			for k, v := range map[string]string{
+				"app_id":    appID,
+				"charset":   "utf-8",
+				"sign_type": "RSA2",
+				"timestamp": s.Timestamp.In(cst).Format("2006-01-02 15:04:05"),
+				"version":   "1.0",
+			} {
+				if s.Params.Get(k) == "" {
+					s.SetParam(k, v)
+				}
+			}
+			return nil
+		},
+		Canonical: func(s *SignContext) (string, error) {
+			return sortedParams(s.Params, []string{"sign"}), nil
+		},
+		Signer: rsaSHA256Signer(key),
+		Encode: base64.StdEncoding.EncodeToString,
+		Apply: func(s *SignContext, sig string) error {
+			s.SetParam("sign", sig)
+			return nil
+		},
+	}
+}
+
+// AliyunSignOptions preset for Aliyun RPC style API with signature version 1.0
+func AliyunSignOptions(accessKeyID, accessKeySecret string) *SignOptions {
+	return &SignOptions{
+		Prepare: func(s *SignContext) error {
+			s.SetParam("AccessKeyId", accessKeyID)
+			s.SetParam("SignatureMethod", "HMAC-SHA1")
+			s.SetParam("SignatureVersion", "1.0")
+			s.SetParam("SignatureNonce", s.Nonce)
+			s.SetParam("Timestamp", s.Timestamp.UTC().Format("2006-01-02T15:04:05Z"))
+			return nil
+		},
+		Canonical: func(s *SignContext) (string, error) {
+			keys := make([]string, 0, len(s.Params))
+			for k := range s.Params {
+				if k != "Signature" {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			pairs := make([]string, 0, len(keys))
+			for _, k := range keys {
+				pairs = append(pairs, aliyunEscape(k)+"="+aliyunEscape(s.Params.Get(k)))
+			}
+			return s.Request.Method + "&" + aliyunEscape("/") + "&" + aliyunEscape(strings.Join(pairs, "&")), nil
+		},
+		Key:    []byte(accessKeySecret + "&"),
+		Hash:   sha1.New,
+		Encode: base64.StdEncoding.EncodeToString,
+		Apply: func(s *SignContext, sig string) error {
+			s.SetParam("Signature", sig)
+			return nil
+		},
+	}
+}
+
+// TencentCloudSignOptions preset for Tencent Cloud API with TC3-HMAC-SHA256
+func TencentCloudSignOptions(secretID, secretKey, service string) *SignOptions {
+	const algorithm = "TC3-HMAC-SHA256"
+	scope := func(s *SignContext) string {
+		return s.Timestamp.UTC().Format("2006-01-02") + "/" + service + "/tc3_request"
+	}
+	return &SignOptions{
+		Canonical: func(s *SignContext) (string, error) {
+			r := s.Request
+			canonical := strings.Join([]string{
+				r.Method,
+				"/",
+				r.URL.RawQuery,
+				"content-type:" + strings.ToLower(r.Header.Get("Content-Type")) + "\nhost:" + requestHeader(r, "Host") + "\n",
+				"content-type;host",
+				hashHex(sha256.New, s.Body),
+			}, "\n")
+			return strings.Join([]string{
+				algorithm,
+				strconv.FormatInt(s.Timestamp.Unix(), 10),
+				scope(s),
+				hashHex(sha256.New, []byte(canonical)),
+			}, "\n"), nil
+		},
+		Signer: func(s *SignContext, data []byte) ([]byte, error) {
+			key := hmacSum(sha256.New, []byte("TC3"+secretKey), []byte(s.Timestamp.UTC().Format("2006-01-02")))
+			key = hmacSum(sha256.New, key, []byte(service))
+			key = hmacSum(sha256.New, key, []byte("tc3_request"))
+			return hmacSum(sha256.New, key, data), nil
+		},
+		Apply: func(s *SignContext, sig string) error {
+			s.Request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s",
+				algorithm, secretID, scope(s), sig))
+			s.Request.Header.Set("X-TC-Timestamp", strconv.FormatInt(s.Timestamp.Unix(), 10))
+			return nil
+		},
+	}
+}
+
+func rsaSHA256Signer(key *rsa.PrivateKey) func(s *SignContext, data []byte) ([]byte, error) {
+	return func(s *SignContext, data []byte) ([]byte, error) {
+		sum := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	}
+}
+
+// requestParams collect query and form body params
+func requestParams(r *http.Request, body []byte) (url.Values, error) {
+	params := r.URL.Query()
+	if len(body) == 0 {
+		return params, nil
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/x-www-form-urlencoded" {
+		return params, nil
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range form {
+		params[k] = append(params[k], v...)
+	}
+	return params, nil
+}
+
+// requestHeader get header include Host
+func requestHeader(r *http.Request, name string) string {
+	if strings.EqualFold(name, "Host") {
+		if r.Host != "" {
+			return r.Host
+		}
+		return r.URL.Host
+	}
+	return r.Header.Get(name)
+}
+
+func sortedParams(params url.Values, exclude []string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+next:
+	for _, k := range keys {
+		for _, e := range exclude {
+			if k == e {
+				continue next
+			}
+		}
+		if v := params.Get(k); v != "" {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func aliyunEscape(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+func hmacSum(h func() hash.Hash, key, data []byte) []byte {
+	m := hmac.New(h, key)
+	m.Write(data)
+	return m.Sum(nil)
+}
+
+func hashHex(h func() hash.Hash, data []byte) string {
+	d := h()
+	d.Write(data)
+	return hex.EncodeToString(d.Sum(nil))
+}
+
+func newNonce() string {
+	b := make([]byte, 16) //nolint:gomnd
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,170 @@
+package req_test
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestSignHook(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	r, err := req.Request{
+		Method:  http.MethodPost,
+		BaseURL: "https://wener.me",
+		URL:     "/pay",
+		Query:   map[string]string{"b": "2", "a": "1"},
+		Body:    map[string]string{"name": "wener"},
+		Options: []interface{}{
+			req.JSONEncode,
+			req.SignHook(&req.SignOptions{
+				Parts: []req.SignPart{
+					req.SignMethod, req.SignPath, req.SignQuery, req.SignTimestamp, req.SignNonce,
+					req.SignHeaders("Content-Type"), req.SignBodyHash(sha256.New),
+				},
+				Key:   []byte("secret"),
+				Now:   func() time.Time { return now },
+				Nonce: func() string { return "nonce" },
+			}),
+		},
+	}.NewRequest()
+	assert.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(`{"name":"wener"}`))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("POST\n/pay\na=1&b=2\n1600000000\nnonce\ncontent-type:application/json;charset=UTF-8\n" +
+		hex.EncodeToString(sum[:])))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), r.Header.Get("Signature"))
+}
+
+func TestSignCanonical(t *testing.T) {
+	var canonical string
+	_, err := req.Request{
+		Method:  http.MethodPost,
+		BaseURL: "https://wener.me",
+		URL:     "/pay?c=3",
+		RawBody: []byte("b=2&a=1"),
+		Header:  http.Header{"Content-Type": {"application/x-www-form-urlencoded"}},
+		Options: []interface{}{
+			req.SignHook(&req.SignOptions{
+				Parts:     []req.SignPart{req.SignMethod, req.SignRequestURI, req.SignParams, req.SignValue("x")},
+				Separator: "|",
+				Terminate: true,
+				Signer: func(s *req.SignContext, data []byte) ([]byte, error) {
+					canonical = string(data)
+					return data, nil
+				},
+			}),
+		},
+	}.NewRequest()
+	assert.NoError(t, err)
+	assert.Equal(t, "POST|/pay?c=3|a=1&b=2&c=3|x|", canonical)
+}
+
+func TestAliyunSign(t *testing.T) {
+	// documented example
+	o := req.AliyunSignOptions("testid", "testsecret")
+	o.Now = func() time.Time { return time.Date(2016, 2, 23, 12, 46, 24, 0, time.UTC) }
+	o.Nonce = func() string { return "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf" }
+	r, err := req.Request{
+		BaseURL: "http://ecs.aliyuncs.com",
+		URL:     "/",
+		Query: map[string]string{
+			"Action":  "DescribeRegions",
+			"Format":  "XML",
+			"Version": "2014-05-26",
+		},
+		Options: []interface{}{
+			req.SignHook(o),
+		},
+	}.NewRequest()
+	assert.NoError(t, err)
+	assert.Equal(t, "OLeaidS1JvxuMvnyHOwuJ+uX5qY=", r.URL.Query().Get("Signature"))
+}
+
+func TestRSASign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	verify := func(msg, sig string) {
+		b, err := base64.StdEncoding.DecodeString(sig)
+		assert.NoError(t, err)
+		sum := sha256.Sum256([]byte(msg))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], b))
+	}
+	{
+		r, err := req.Request{
+			Method:  http.MethodPost,
+			BaseURL: "https://api.mch.weixin.qq.com",
+			URL:     "/v3/pay/transactions/jsapi",
+			RawBody: []byte(`{"appid":"wx"}`),
+			Options: []interface{}{req.SignHook(req.WechatPaySignOptions("1900009191", "SERIAL", key))},
+		}.NewRequest()
+		assert.NoError(t, err)
+		m := regexp.MustCompile(`^WECHATPAY2-SHA256-RSA2048 mchid="1900009191",nonce_str="(\w+)",signature="([^"]+)",timestamp="(\d+)",serial_no="SERIAL"$`).
+			FindStringSubmatch(r.Header.Get("Authorization"))
+		assert.Len(t, m, 4)
+		verify("POST\n/v3/pay/transactions/jsapi\n"+m[3]+"\n"+m[1]+"\n"+`{"appid":"wx"}`+"\n", m[2])
+	}
+	{
+		r, err := req.Request{
+			Method:  http.MethodPost,
+			BaseURL: "https://openapi.alipay.com/gateway.do",
+			Query:   map[string]string{"method": "alipay.trade.query"},
+			Body:    map[string]string{"biz_content": `{"out_trade_no":"1"}`},
+			Options: []interface{}{req.FormEncode, req.SignHook(req.AlipaySignOptions("2014072300007148", key))},
+		}.NewRequest()
+		assert.NoError(t, err)
+		q := r.URL.Query()
+		sig := q.Get("sign")
+		q.Del("sign")
+		q.Set("biz_content", `{"out_trade_no":"1"}`)
+		msg, _ := url.QueryUnescape(q.Encode())
+		verify(msg, sig)
+		assert.Equal(t, "RSA2", q.Get("sign_type"))
+	}
+}
+
+func TestTencentCloudSign(t *testing.T) {
+	r, err := req.Request{
+		Method:  http.MethodPost,
+		BaseURL: "https://cvm.tencentcloudapi.com",
+		URL:     "/",
+		Body:    map[string]int{"Limit": 1},
+		Header:  http.Header{"X-TC-Action": {"DescribeInstances"}},
+		Options: []interface{}{req.JSONEncode, req.SignHook(req.TencentCloudSignOptions("AKID", "KEY", "cvm"))},
+	}.NewRequest()
+	assert.NoError(t, err)
+	assert.Regexp(t, `^TC3-HMAC-SHA256 Credential=AKID/\d{4}-\d{2}-\d{2}/cvm/tc3_request, SignedHeaders=content-type;host, Signature=[0-9a-f]{64}$`, r.Header.Get("Authorization"))
+	assert.NotEmpty(t, r.Header.Get("X-TC-Timestamp"))
+
+	// documented example
+	o := req.TencentCloudSignOptions("AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE", "Gu5t9xGARNpq86cd98joQYCN3EXAMPLE", "cvm")
+	o.Now = func() time.Time { return time.Unix(1551113065, 0) }
+	r, err = req.Request{
+		Method:  http.MethodPost,
+		BaseURL: "https://cvm.tencentcloudapi.com",
+		URL:     "/",
+		RawBody: []byte(`{"Limit": 1, "Filters": [{"Values": ["\u672a\u547d\u540d"], "Name": "instance-name"}]}`),
+		Header: http.Header{
+			"Content-Type": {"application/json; charset=utf-8"},
+			"X-TC-Action":  {"DescribeInstances"},
+		},
+		Options: []interface{}{req.SignHook(o)},
+	}.NewRequest()
+	assert.NoError(t, err)
+	assert.Equal(t, "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3EXAMPLE/2019-02-25/cvm/tc3_request, "+
+		"SignedHeaders=content-type;host, Signature=72e494ea809ad7a8c8f7a4507b9bddcbaa8e581f516e8da2f66e2c5a96525168",
+		r.Header.Get("Authorization"))
+	assert.Equal(t, "1551113065", r.Header.Get("X-TC-Timestamp"))
+}
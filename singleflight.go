@@ -0,0 +1,129 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// SingleflightOptions options for SingleflightHook
+type SingleflightOptions struct {
+	Methods []string                     // Methods allow coalescing, default GET and HEAD
+	Headers []string                     // Headers included in key, e.g. Authorization, Accept
+	Key     func(r *http.Request) string // Key override default key of method, url and Headers
+}
+
+type singleflightCall struct {
+	wg   sync.WaitGroup
+	res  *http.Response
+	body []byte
+	err  error
+}
+
+// SingleflightHook coalesce identical in-flight requests, only one request goes to network,
+// the response is buffered and every caller got independent body.
+//
+// Request with body is never coalesced.
+func SingleflightHook(o *SingleflightOptions) Hook {
+	if o == nil {
+		o = &SingleflightOptions{}
+	}
+	methods := o.Methods
+	if methods == nil {
+		methods = []string{http.MethodGet, http.MethodHead}
+	}
+	key := o.Key
+	if key == nil {
+		key = func(r *http.Request) string {
+			sb := strings.Builder{}
+			sb.WriteString(r.Method)
+			sb.WriteByte(' ')
+			sb.WriteString(r.URL.String())
+			for _, v := range o.Headers {
+				sb.WriteByte('\n')
+				sb.WriteString(http.CanonicalHeaderKey(v))
+				sb.WriteByte(':')
+				sb.WriteString(strings.Join(r.Header.Values(v), ","))
+			}
+			return sb.String()
+		}
+	}
+	var mu sync.Mutex
+	calls := map[string]*singleflightCall{}
+	coalesce := func(r *http.Request) bool {
+		if r.Body != nil && r.Body != http.NoBody {
+			return false
+		}
+		for _, v := range methods {
+			if v == r.Method {
+				return true
+			}
+		}
+		return false
+	}
+	return Hook{
+		Name:  "Singleflight",
+		Order: -50,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if !coalesce(r) {
+					return next.RoundTrip(r)
+				}
+				k := key(r)
+				mu.Lock()
+				if c, ok := calls[k]; ok {
+					mu.Unlock()
+					done := make(chan struct{})
+					go func() {
+						c.wg.Wait()
+						close(done)
+					}()
+					select {
+					case <-done:
+					case <-r.Context().Done():
+						return nil, r.Context().Err()
+					}
+					// leader canceled by its caller
+					if c.err != nil && (c.err == context.Canceled || c.err == context.DeadlineExceeded) && r.Context().Err() == nil {
+						return next.RoundTrip(r)
+					}
+					return c.response(r)
+				}
+				c := &singleflightCall{}
+				c.wg.Add(1)
+				calls[k] = c
+				mu.Unlock()
+
+				c.res, c.err = next.RoundTrip(r)
+				if c.err == nil {
+					c.body, c.err = io.ReadAll(c.res.Body)
+					_ = c.res.Body.Close()
+				}
+				if c.err != nil && r.Context().Err() != nil {
+					c.err = r.Context().Err()
+				}
+				mu.Lock()
+				delete(calls, k)
+				mu.Unlock()
+				c.wg.Done()
+				return c.response(r)
+			})
+		},
+	}
+}
+
+func (c *singleflightCall) response(r *http.Request) (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	res := *c.res
+	res.Header = c.res.Header.Clone()
+	res.Trailer = c.res.Trailer.Clone()
+	res.Body = io.NopCloser(bytes.NewReader(c.body))
+	res.ContentLength = int64(len(c.body))
+	res.Request = r
+	return &res, nil
+}
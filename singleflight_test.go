@@ -0,0 +1,91 @@
+package req_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestSingleflightHook(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		_, _ = io.WriteString(w, r.URL.Path+" "+r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	client := req.Request{BaseURL: server.URL}.WithHook(req.SingleflightHook(&req.SingleflightOptions{Headers: []string{"Authorization"}}))
+	slow := client.With(req.Request{URL: "/slow"})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, _, err := slow.FetchString()
+			assert.NoError(t, err)
+			assert.Equal(t, "/slow ", out)
+		}()
+	}
+	// different header not coalesced
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		out, _, err := slow.With(req.Request{Header: http.Header{"Authorization": []string{"a"}}}).FetchString()
+		assert.NoError(t, err)
+		assert.Equal(t, "/slow a", out)
+	}()
+	// post not coalesced
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := slow.With(req.Request{Method: http.MethodPost}).Do()
+			assert.NoError(t, err)
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&calls) == 4
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 4, atomic.LoadInt32(&calls))
+
+	// not in-flight anymore
+	_, _, err := slow.FetchString()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 5, atomic.LoadInt32(&calls))
+
+	// waiter canceled
+	block := make(chan struct{})
+	blocking := req.Request{BaseURL: server.URL}.WithHook(
+		req.UseRoundTripper(rtFunc(func(r *http.Request) (*http.Response, error) {
+			<-block
+			return http.DefaultTransport.RoundTrip(r)
+		})),
+		req.SingleflightHook(nil),
+	)
+	go func() {
+		_, _ = blocking.Do()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = blocking.With(req.Request{Context: ctx}).Do()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	close(block)
+}
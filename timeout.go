@@ -0,0 +1,30 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// TimeoutHook limit round trip to d including read of response body, per attempt when used with RetryHook
+func TimeoutHook(d time.Duration) Hook {
+	return Hook{
+		Name:  "Timeout",
+		Order: -42,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if d <= 0 {
+					return next.RoundTrip(r)
+				}
+				ctx, cancel := context.WithTimeout(r.Context(), d)
+				res, err := next.RoundTrip(r.WithContext(ctx))
+				if err != nil {
+					cancel()
+					return nil, err
+				}
+				onBodyClose(res, cancel)
+				return res, nil
+			})
+		},
+	}
+}
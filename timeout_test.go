@@ -0,0 +1,51 @@
+package req_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestTimeoutHook(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 || r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := req.Request{BaseURL: server.URL}.WithHook(req.TimeoutHook(50 * time.Millisecond))
+	_, err := client.With(req.Request{URL: "/slow"}).Do()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+
+	// body readable after round trip until closed
+	atomic.StoreInt32(&calls, 1)
+	res, err := client.Do()
+	assert.NoError(t, err)
+	time.Sleep(60 * time.Millisecond)
+	b, err := io.ReadAll(res.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(b))
+	assert.NoError(t, res.Body.Close())
+
+	// per attempt timeout with retry
+	atomic.StoreInt32(&calls, 0)
+	s, _, err := client.WithHook(req.RetryHook(&req.RetryOptions{Backoff: time.Millisecond})).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", s)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,174 @@
+package req
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPinMismatch returned when none of server certificates match the pin set
+var ErrPinMismatch = errors.New("req: certificate pin mismatch")
+
+// TLSOptions options for TLSHook
+type TLSOptions struct {
+	CertFile           string   // CertFile client certificate file, used with KeyFile
+	KeyFile            string   // KeyFile client private key file
+	CertPEM            []byte   // CertPEM client certificate, used with KeyPEM
+	KeyPEM             []byte   // KeyPEM client private key
+	RootCAFiles        []string // RootCAFiles extra root CA files added to system pool
+	RootCAPEM          []byte   // RootCAPEM extra root CA added to system pool
+	Pins               []string // Pins base64 encoded SHA-256 of SPKI, "sha256/" prefix is allowed
+	MinVersion         uint16   // MinVersion e.g. tls.VersionTLS12
+	ServerName         string   // ServerName override SNI and verified host name
+	InsecureSkipVerify bool     // InsecureSkipVerify skip chain verify, pins are still checked
+}
+
+// TLSHook build and cache transport with TLS options
+func TLSHook(o *TLSOptions) Hook {
+	h := transportHook("TLS", 100, func(t *http.Transport) error {
+		if o == nil {
+			return nil
+		}
+		c, err := o.Config()
+		if err != nil {
+			return err
+		}
+		if t.TLSClientConfig != nil {
+			// keep settings from inner hooks
+			c.NextProtos = t.TLSClientConfig.NextProtos
+		}
+		t.TLSClientConfig = c
+		return nil
+	})
+	if o != nil {
+		h.curl = o.curl
+	}
+	return h
+}
+
+// curl flags of options
+func (o TLSOptions) curl(r *http.Request) (args, comments []string) {
+	if r.URL.Scheme != "https" {
+		return
+	}
+	if o.InsecureSkipVerify {
+		args = append(args, "-k")
+	}
+	switch o.MinVersion {
+	case tls.VersionTLS10:
+		args = append(args, "--tlsv1.0")
+	case tls.VersionTLS11:
+		args = append(args, "--tlsv1.1")
+	case tls.VersionTLS12:
+		args = append(args, "--tlsv1.2")
+	case tls.VersionTLS13:
+		args = append(args, "--tlsv1.3")
+	}
+	if o.CertFile != "" {
+		args = append(args, "--cert "+shellQuote(o.CertFile))
+	}
+	if o.KeyFile != "" {
+		args = append(args, "--key "+shellQuote(o.KeyFile))
+	}
+	switch {
+	case len(o.RootCAFiles) == 1:
+		args = append(args, "--cacert "+shellQuote(o.RootCAFiles[0]))
+	case len(o.RootCAFiles) > 1:
+		comments = append(comments, "# unsupported: TLS RootCAFiles "+strings.Join(o.RootCAFiles, ",")+", concat them for --cacert")
+	}
+	if len(o.Pins) > 0 {
+		pins := make([]string, 0, len(o.Pins))
+		for _, v := range o.Pins {
+			pins = append(pins, "sha256//"+strings.TrimPrefix(v, "sha256/"))
+		}
+		args = append(args, "--pinnedpubkey "+shellQuote(strings.Join(pins, ";")))
+	}
+	if o.CertPEM != nil || o.KeyPEM != nil {
+		comments = append(comments, "# unsupported: TLS CertPEM and KeyPEM, save them for --cert and --key")
+	}
+	if len(o.RootCAPEM) > 0 {
+		comments = append(comments, "# unsupported: TLS RootCAPEM, save it for --cacert")
+	}
+	if o.ServerName != "" {
+		comments = append(comments, "# unsupported: TLS ServerName "+o.ServerName)
+	}
+	return
+}
+
+// Config build tls.Config
+func (o TLSOptions) Config() (*tls.Config, error) {
+	c := &tls.Config{
+		MinVersion:         o.MinVersion,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify, //nolint:gosec
+	}
+	if c.MinVersion == 0 {
+		c.MinVersion = tls.VersionTLS12
+	}
+
+	switch {
+	case o.CertFile != "" || o.KeyFile != "":
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate")
+		}
+		c.Certificates = []tls.Certificate{cert}
+	case o.CertPEM != nil || o.KeyPEM != nil:
+		cert, err := tls.X509KeyPair(o.CertPEM, o.KeyPEM)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse client certificate")
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(o.RootCAFiles) > 0 || len(o.RootCAPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, v := range o.RootCAFiles {
+			b, err := os.ReadFile(v)
+			if err != nil {
+				return nil, errors.Wrap(err, "read root CA")
+			}
+			if !pool.AppendCertsFromPEM(b) {
+				return nil, errors.Errorf("no certificate found in root CA file %s", v)
+			}
+		}
+		if len(o.RootCAPEM) > 0 && !pool.AppendCertsFromPEM(o.RootCAPEM) {
+			return nil, errors.New("no certificate found in root CA PEM")
+		}
+		c.RootCAs = pool
+	}
+
+	if len(o.Pins) > 0 {
+		pins := make(map[string]bool, len(o.Pins))
+		for _, v := range o.Pins {
+			pins[strings.TrimPrefix(v, "sha256/")] = true
+		}
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			got := make([]string, 0, len(cs.PeerCertificates))
+			for _, cert := range cs.PeerCertificates {
+				pin := SPKIPin(cert)
+				if pins[pin] {
+					return nil
+				}
+				got = append(got, pin)
+			}
+			return errors.Wrapf(ErrPinMismatch, "%s presented %s", cs.ServerName, strings.Join(got, ","))
+		}
+	}
+	return c, nil
+}
+
+// SPKIPin return base64 encoded SHA-256 of certificate SPKI
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
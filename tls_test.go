@@ -0,0 +1,118 @@
+package req_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestTLSHook(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	pin := req.SPKIPin(server.Certificate())
+	certPEM, keyPEM := generateCert(t, "client")
+
+	fetch := func(o *req.TLSOptions) (string, error) {
+		out, _, err := req.Request{BaseURL: server.URL}.WithHook(req.TLSHook(o)).FetchString()
+		return out, err
+	}
+	{
+		_, _, err := req.Request{BaseURL: server.URL}.FetchString()
+		assert.Error(t, err)
+	}
+	{
+		out, err := fetch(&req.TLSOptions{RootCAPEM: ca})
+		assert.NoError(t, err)
+		assert.Equal(t, "", out)
+	}
+	{
+		out, err := fetch(&req.TLSOptions{RootCAPEM: ca, CertPEM: certPEM, KeyPEM: keyPEM, MinVersion: tls.VersionTLS13})
+		assert.NoError(t, err)
+		assert.Equal(t, "client", out)
+	}
+	{
+		_, err := fetch(&req.TLSOptions{RootCAPEM: ca, Pins: []string{"sha256/" + pin}})
+		assert.NoError(t, err)
+
+		_, err = fetch(&req.TLSOptions{InsecureSkipVerify: true, Pins: []string{"sha256/AAAA"}})
+		assert.True(t, errors.Is(err, req.ErrPinMismatch), "%v", err)
+		assert.Contains(t, err.Error(), pin)
+	}
+	{
+		// certificate of httptest is issued for example.com
+		_, err := fetch(&req.TLSOptions{RootCAPEM: ca, ServerName: "example.com"})
+		assert.NoError(t, err)
+		_, err = fetch(&req.TLSOptions{RootCAPEM: ca, ServerName: "wener.me"})
+		assert.Error(t, err)
+	}
+	{
+		_, err := fetch(&req.TLSOptions{CertFile: "missing.pem", KeyFile: "missing.key"})
+		assert.Error(t, err)
+	}
+}
+
+func TestTLSHookReuse(t *testing.T) {
+	var transports []http.RoundTripper
+	client := req.Request{}.WithHook(req.TLSHook(&req.TLSOptions{InsecureSkipVerify: true}), req.Hook{
+		Order: 1,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			transports = append(transports, next)
+			return next
+		},
+	})
+	for i := 0; i < 2; i++ {
+		r, err := client.With(req.Request{URL: "https://wener.me"}).NewRequest()
+		assert.NoError(t, err)
+		_, _ = req.FromContext(r.Context()).Extension.RoundTrip(r.Clone(canceledContext()))
+	}
+	assert.Len(t, transports, 2)
+	assert.Same(t, transports[0], transports[1])
+	assert.NotSame(t, http.DefaultTransport, transports[0])
+}
+
+func generateCert(t *testing.T, cn string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
@@ -0,0 +1,169 @@
+package req
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultDialer same as http.DefaultTransport
+var defaultDialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second} //nolint:gomnd
+
+// transportHook derive a configured *http.Transport from next, derived transport is built once per base transport
+// and shared by all copies of the Hook, so connection pool is reused across Request.With.
+func transportHook(name string, order int, configure func(t *http.Transport) error) Hook {
+	var mu sync.Mutex
+	cache := map[*http.Transport]*http.Transport{}
+	return Hook{
+		Name:  name,
+		Order: order,
+		HandleRequest: func(next http.RoundTripper) http.RoundTripper {
+			base, ok := next.(*http.Transport)
+			if !ok {
+				return errorRoundTripper(errors.Errorf("%s: require *http.Transport got %T", name, next))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if t, ok := cache[base]; ok {
+				return t
+			}
+			t := base.Clone()
+			if err := configure(t); err != nil {
+				return errorRoundTripper(errors.Wrap(err, name))
+			}
+			cache[base] = t
+			return t
+		},
+	}
+}
+
+// TransportOptions options for TransportHook, zero value keep the default of base transport
+type TransportOptions struct {
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int
+	IdleConnTimeout       time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	DialTimeout           time.Duration // DialTimeout of TCP connect
+	KeepAlive             time.Duration // KeepAlive period of TCP connection, negative disable
+	DisableKeepAlives     bool          // DisableKeepAlives disable HTTP keep-alive, one connection per request
+	DisableHTTP2          bool          // DisableHTTP2 disable HTTP/2 negotiation
+	DisableCompression    bool          // DisableCompression disable transparent gzip
+}
+
+var transportHooks sync.Map
+
+// TransportHook build own transport and connection pool, hook is shared by options value,
+// so one transport is built for each distinct options and reused by all Request use it
+func TransportHook(o *TransportOptions) Hook {
+	if o == nil {
+		o = &TransportOptions{}
+	}
+	key := *o
+	if h, ok := transportHooks.Load(key); ok {
+		return h.(Hook)
+	}
+	h := transportHook("Transport", 110, func(t *http.Transport) error {
+		o := key // options may be changed by caller after
+		if o.MaxIdleConns > 0 {
+			t.MaxIdleConns = o.MaxIdleConns
+		}
+		if o.MaxIdleConnsPerHost > 0 {
+			t.MaxIdleConnsPerHost = o.MaxIdleConnsPerHost
+		}
+		if o.MaxConnsPerHost > 0 {
+			t.MaxConnsPerHost = o.MaxConnsPerHost
+		}
+		if o.IdleConnTimeout > 0 {
+			t.IdleConnTimeout = o.IdleConnTimeout
+		}
+		if o.TLSHandshakeTimeout > 0 {
+			t.TLSHandshakeTimeout = o.TLSHandshakeTimeout
+		}
+		if o.ResponseHeaderTimeout > 0 {
+			t.ResponseHeaderTimeout = o.ResponseHeaderTimeout
+		}
+		if o.DialTimeout != 0 || o.KeepAlive != 0 {
+			d := &net.Dialer{Timeout: defaultDialer.Timeout, KeepAlive: defaultDialer.KeepAlive}
+			if o.DialTimeout != 0 {
+				d.Timeout = o.DialTimeout
+			}
+			if o.KeepAlive != 0 {
+				d.KeepAlive = o.KeepAlive
+			}
+			t.DialContext = d.DialContext
+		}
+		t.DisableKeepAlives = t.DisableKeepAlives || o.DisableKeepAlives
+		t.DisableCompression = t.DisableCompression || o.DisableCompression
+		if o.DisableHTTP2 {
+			t.ForceAttemptHTTP2 = false
+			t.TLSNextProto = map[string]func(authority string, c *tls.Conn) http.RoundTripper{}
+			if t.TLSClientConfig != nil {
+				var protos []string
+				for _, v := range t.TLSClientConfig.NextProtos {
+					if v != "h2" {
+						protos = append(protos, v)
+					}
+				}
+				t.TLSClientConfig.NextProtos = protos
+			}
+		}
+		return nil
+	})
+	if key.DisableHTTP2 {
+		h.curl = func(r *http.Request) (args, comments []string) {
+			return []string{"--http1.1"}, nil
+		}
+	}
+	v, _ := transportHooks.LoadOrStore(key, h)
+	return v.(Hook)
+}
+
+// DialFunc dial connection for transport
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DialHook use custom dial for transport, dial is wrapped by ResolveHook
+func DialHook(dial DialFunc) Hook {
+	// base dialer is set before dial wrappers
+	return transportHook("Dial", 105, func(t *http.Transport) error {
+		t.DialContext = dial
+		return nil
+	})
+}
+
+var unixSocketHooks sync.Map
+
+// UnixSocketHook connect to unix domain socket at path for all requests, host of request url is ignored.
+//
+// Hook is shared by path, Request with BaseURL like unix:///var/run/docker.sock use this hook.
+func UnixSocketHook(path string) Hook {
+	if h, ok := unixSocketHooks.Load(path); ok {
+		return h.(Hook)
+	}
+	h := transportHook("UnixSocket", 105, func(t *http.Transport) error {
+		dial := transportDial(t)
+		t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dial(ctx, "unix", path)
+		}
+		return nil
+	})
+	h.curl = func(r *http.Request) (args, comments []string) {
+		return []string{"--unix-socket " + shellQuote(path)}, nil
+	}
+	v, _ := unixSocketHooks.LoadOrStore(path, h)
+	return v.(Hook)
+}
+
+// transportDial return dial of transport or default dialer
+func transportDial(t *http.Transport) DialFunc {
+	if t.DialContext != nil {
+		return t.DialContext
+	}
+	return defaultDialer.DialContext
+}
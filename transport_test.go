@@ -0,0 +1,102 @@
+package req_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wenerme/go-req"
+)
+
+func TestTransportHook(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Accept-Encoding")))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	count := func(o *req.TransportOptions) (int32, string) {
+		atomic.StoreInt32(&conns, 0)
+		client := req.Request{BaseURL: server.URL}.WithHook(req.TransportHook(o))
+		var out string
+		for i := 0; i < 3; i++ {
+			var err error
+			out, _, err = client.With(req.Request{URL: "/"}).FetchString()
+			assert.NoError(t, err)
+		}
+		return atomic.LoadInt32(&conns), out
+	}
+
+	n, out := count(&req.TransportOptions{MaxIdleConnsPerHost: 1})
+	assert.EqualValues(t, 1, n)
+	assert.Equal(t, "gzip", out)
+
+	n, out = count(&req.TransportOptions{DisableKeepAlives: true, DisableCompression: true})
+	assert.EqualValues(t, 3, n)
+	assert.Equal(t, "", out)
+
+	// same options share the pool
+	atomic.StoreInt32(&conns, 0)
+	for i := 0; i < 3; i++ {
+		_, _, err := req.Request{BaseURL: server.URL}.WithHook(req.TransportHook(&req.TransportOptions{MaxIdleConnsPerHost: 2})).FetchString()
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&conns))
+}
+
+func TestTransportHookHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client := req.Request{BaseURL: server.URL}.WithHook(req.TLSHook(&req.TLSOptions{InsecureSkipVerify: true}))
+	out, _, err := client.WithHook(req.TransportHook(&req.TransportOptions{})).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", out)
+
+	out, _, err = client.WithHook(req.TransportHook(&req.TransportOptions{DisableHTTP2: true})).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1", out)
+}
+
+func TestUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "req.sock")
+	l, err := net.Listen("unix", sock)
+	assert.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	})}
+	go func() { _ = server.Serve(l) }()
+	defer server.Close()
+
+	out, _, err := req.Request{BaseURL: "unix://" + sock, URL: "/v1.41/info"}.FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "/v1.41/info", out)
+
+	out, _, err = req.Request{BaseURL: "http://docker", URL: "/_ping"}.WithHook(req.UnixSocketHook(sock)).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "/_ping", out)
+
+	dialed := ""
+	out, _, err = req.Request{BaseURL: "http://agent:1234", URL: "/dial"}.WithHook(req.DialHook(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+	})).FetchString()
+	assert.NoError(t, err)
+	assert.Equal(t, "/dial", out)
+	assert.Equal(t, "agent:1234", dialed)
+}
@@ -1,5 +1,12 @@
 package req
 
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
 func mergeMapSliceString(a map[string][]string, b map[string][]string) map[string][]string {
 	if len(a) == 0 {
 		return cloneMapSliceString(b)
@@ -34,3 +41,66 @@ func cloneMapSliceString(h map[string][]string) map[string][]string {
 	}
 	return h2
 }
+
+// requestBody return encoded body of http.Request, prefer reconciled RawBody of Request in context
+func requestBody(r *http.Request) ([]byte, error) {
+	if re := FromContext(r.Context()); re != nil && re.RawBody != nil {
+		return re.RawBody, nil
+	}
+	if r.GetBody == nil {
+		return nil, nil
+	}
+	b, err := r.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+	return io.ReadAll(b)
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// errorRoundTripper fail every request with err
+func errorRoundTripper(err error) http.RoundTripper {
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, err
+	})
+}
+
+type closeNotifyBody struct {
+	io.ReadCloser
+	once sync.Once
+	fn   func()
+}
+
+func (b *closeNotifyBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.fn)
+	return err
+}
+
+// onBodyClose call fn once when response body closed, call fn directly if no body
+func onBodyClose(res *http.Response, fn func()) {
+	if res == nil || res.Body == nil {
+		fn()
+		return
+	}
+	res.Body = &closeNotifyBody{ReadCloser: res.Body, fn: fn}
+}
+
+// HostKey key request by url host, used by per host hooks
+func HostKey(r *http.Request) string {
+	return r.URL.Host
+}
+
+// cutString slice s around the first sep, same as strings.Cut of go 1.18
+func cutString(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}